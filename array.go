@@ -0,0 +1,171 @@
+package pystruct
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
+)
+
+// PackArray packs the records one after another according to the format string format,
+// each record must hold the values required by the format exactly
+func (s *PyStruct) PackArray(records [][]interface{}) ([]byte, error) {
+	buffer := make([]byte, 0, len(records)*s.size)
+	for i, record := range records {
+		var err error
+		if buffer, err = s.AppendPack(buffer, record...); err != nil {
+			return nil, fmt.Errorf("record %d: %w", i, err)
+		}
+	}
+	return buffer, nil
+}
+
+// UnpackArray unpacks the records packed one after another in buffer,
+// the buffer’s size in bytes must be a multiple of the size required by the format
+func (s *PyStruct) UnpackArray(buffer []byte) ([][]interface{}, error) {
+	chunks, err := s.records(buffer)
+	if err != nil {
+		return nil, err
+	}
+	records := make([][]interface{}, len(chunks))
+	for i, chunk := range chunks {
+		if err := s.verify(chunk); err != nil {
+			return nil, fmt.Errorf("record %d: %w", i, err)
+		}
+		records[i] = s.unpackValues(chunk)
+	}
+	return records, nil
+}
+
+// parallelRanges splits n records into contiguous ranges processed by fn concurrently,
+// workers <= 0 means runtime.GOMAXPROCS(0) workers.
+// The error of the first failed record is returned.
+func parallelRanges(n, workers int, fn func(start, end int) (int, error)) error {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > n {
+		workers = n
+	}
+	if workers == 0 {
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	failed := make([]int, workers)
+	errs := make([]error, workers)
+	chunk := (n + workers - 1) / workers
+
+	for w := 0; w < workers; w++ {
+		start, end := w*chunk, (w+1)*chunk
+		if end > n {
+			end = n
+		}
+		wg.Add(1)
+		go func(w, start, end int) {
+			defer wg.Done()
+			failed[w], errs[w] = fn(start, end)
+		}(w, start, end)
+	}
+	wg.Wait()
+
+	for w := range errs {
+		if errs[w] != nil {
+			return fmt.Errorf("record %d: %w", failed[w], errs[w])
+		}
+	}
+	return nil
+}
+
+// PackArrayParallel works like PackArray, but packs contiguous ranges of records concurrently,
+// workers <= 0 means runtime.GOMAXPROCS(0) workers
+func (s *PyStruct) PackArrayParallel(records [][]interface{}, workers int) ([]byte, error) {
+	buffer := make([]byte, len(records)*s.size)
+	err := parallelRanges(len(records), workers, func(start, end int) (int, error) {
+		for i := start; i < end; i++ {
+			// the record slot has enough capacity, so AppendPack writes in place
+			if _, err := s.AppendPack(buffer[i*s.size:i*s.size], records[i]...); err != nil {
+				return i, err
+			}
+		}
+		return end, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buffer, nil
+}
+
+// UnpackArrayParallel works like UnpackArray, but unpacks contiguous ranges of records concurrently,
+// workers <= 0 means runtime.GOMAXPROCS(0) workers
+func (s *PyStruct) UnpackArrayParallel(buffer []byte, workers int) ([][]interface{}, error) {
+	chunks, err := s.records(buffer)
+	if err != nil {
+		return nil, err
+	}
+	records := make([][]interface{}, len(chunks))
+	err = parallelRanges(len(chunks), workers, func(start, end int) (int, error) {
+		for i := start; i < end; i++ {
+			if err := s.verify(chunks[i]); err != nil {
+				return i, err
+			}
+			records[i] = s.unpackValues(chunks[i])
+		}
+		return end, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// PackSlice packs the elements of a slice of Go structs one after another.
+// Exported fields are bound to the format items in declaration order,
+// a field tagged `pystruct:"-"` is skipped, an array field is bound to an item per element
+// and a string, []byte or [N]byte field is bound to an 's' item.
+func (s *PyStruct) PackSlice(slice interface{}) ([]byte, error) {
+	v := reflect.ValueOf(slice)
+	if v.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("struct.error: PackSlice requires a slice, got %T", slice)
+	}
+	binding, err := s.bindStruct(v.Type().Elem())
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := make([]byte, 0, v.Len()*s.size)
+	for i := 0; i < v.Len(); i++ {
+		if buffer, err = s.AppendPack(buffer, binding.values(v.Index(i))...); err != nil {
+			return nil, fmt.Errorf("record %d: %w", i, err)
+		}
+	}
+	return buffer, nil
+}
+
+// UnpackSlice unpacks the records packed one after another in buffer into the slice of Go structs
+// pointed to by slicePtr, the fields are bound like PackSlice does.
+// The buffer’s size in bytes must be a multiple of the size required by the format.
+func (s *PyStruct) UnpackSlice(buffer []byte, slicePtr interface{}) error {
+	v := reflect.ValueOf(slicePtr)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("struct.error: UnpackSlice requires a pointer to a slice, got %T", slicePtr)
+	}
+	binding, err := s.bindStruct(v.Elem().Type().Elem())
+	if err != nil {
+		return err
+	}
+	chunks, err := s.records(buffer)
+	if err != nil {
+		return err
+	}
+
+	out := reflect.MakeSlice(v.Elem().Type(), len(chunks), len(chunks))
+	for i, chunk := range chunks {
+		if err := s.verify(chunk); err != nil {
+			return fmt.Errorf("record %d: %w", i, err)
+		}
+		binding.set(out.Index(i), s.unpack(chunk))
+	}
+	v.Elem().Set(out)
+	return nil
+}
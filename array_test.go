@@ -0,0 +1,155 @@
+package pystruct
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestPackArray(t *testing.T) {
+	s, err := NewStruct("<bH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := [][]interface{}{
+		{int8(1), uint16(2)},
+		{int8(-1), uint16(65535)},
+	}
+
+	buffer, err := s.PackArray(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{1, 2, 0, 0xff, 0xff, 0xff}
+	if !bytes.Equal(buffer, expected) {
+		t.Errorf("Expected: %v\nActual: %v\n", expected, buffer)
+	}
+
+	unpacked, err := s.UnpackArray(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(unpacked, records) {
+		t.Errorf("Expected: %v\nActual: %v\n", records, unpacked)
+	}
+
+	if _, err := s.UnpackArray(buffer[1:]); err == nil {
+		t.Error("expected error for a buffer which is not a multiple of the size")
+	}
+	if _, err := s.PackArray([][]interface{}{{int8(1), uint16(2)}, {int8(1)}}); err == nil {
+		t.Error("expected error for a wrong record")
+	}
+}
+
+func TestPackArrayParallel(t *testing.T) {
+	s, err := NewStruct("@bq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := make([][]interface{}, 1000)
+	for i := range records {
+		records[i] = []interface{}{int8(i), int64(i * 1000)}
+	}
+
+	expected, err := s.PackArray(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, workers := range []int{0, 1, 3, 2000} {
+		buffer, err := s.PackArrayParallel(records, workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buffer, expected) {
+			t.Errorf("%d workers: packed buffers differ", workers)
+		}
+
+		unpacked, err := s.UnpackArrayParallel(buffer, workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(unpacked, records) {
+			t.Errorf("%d workers: unpacked records differ", workers)
+		}
+	}
+
+	records[700] = []interface{}{"wrong", int64(0)}
+	if _, err := s.PackArrayParallel(records, 4); err == nil {
+		t.Error("expected error for a wrong record")
+	}
+}
+
+type sampleKind uint8
+
+type sampleRecord struct {
+	Kind    sampleKind
+	Label   [3]byte
+	XY      [2]int16
+	Value   float64
+	Name    string
+	ignored int
+	Skipped string `pystruct:"-"`
+}
+
+func TestPackSlice(t *testing.T) {
+	s, err := NewStruct("<B3s2hd4s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := []sampleRecord{
+		{Kind: 1, Label: [3]byte{'a', 'b', 'c'}, XY: [2]int16{1, -1}, Value: 1.5, Name: "name"},
+		{Kind: 2, Label: [3]byte{'x', 0, 0}, XY: [2]int16{0, 7}, Value: -2, Name: "n"},
+	}
+
+	buffer, err := s.PackSlice(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := s.PackArray([][]interface{}{
+		{uint8(1), "abc", int16(1), int16(-1), 1.5, "name"},
+		{uint8(2), "x", int16(0), int16(7), -2.0, "n"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buffer, expected) {
+		t.Errorf("Expected: %v\nActual: %v\n", expected, buffer)
+	}
+
+	var unpacked []sampleRecord
+	if err := s.UnpackSlice(buffer, &unpacked); err != nil {
+		t.Fatal(err)
+	}
+	records[1].Name = "n\x00\x00\x00"
+	if !reflect.DeepEqual(unpacked, records) {
+		t.Errorf("Expected: %+v\nActual: %+v\n", records, unpacked)
+	}
+}
+
+func TestPackSliceBindingErrors(t *testing.T) {
+	type wrongType struct {
+		A int32
+		B float64
+	}
+	type tooFew struct {
+		A int8
+	}
+
+	s, err := NewStruct("<bd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.PackSlice([]wrongType{{}}); err == nil {
+		t.Error("expected error for a wrong field type")
+	}
+	if _, err := s.PackSlice([]tooFew{{}}); err == nil {
+		t.Error("expected error for missing fields")
+	}
+	if _, err := s.PackSlice([]int{1}); err == nil {
+		t.Error("expected error for a slice of non struct type")
+	}
+	if err := s.UnpackSlice(make([]byte, 9), []tooFew{}); err == nil {
+		t.Error("expected error for a non pointer argument")
+	}
+}
@@ -0,0 +1,188 @@
+package pystruct
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"reflect"
+)
+
+// Uint128 is an unsigned 128-bit integer, the value of the items set by WithUint128
+type Uint128 struct {
+	Hi uint64 // most significant 64 bits
+	Lo uint64 // least significant 64 bits
+}
+
+// Big returns the value as a big.Int
+func (u Uint128) Big() *big.Int {
+	n := new(big.Int).SetUint64(u.Hi)
+	return n.Lsh(n, 64).Or(n, new(big.Int).SetUint64(u.Lo))
+}
+
+func (u Uint128) String() string {
+	return u.Big().String()
+}
+
+// format characters of the integers packed as 's' items, declared like with the modifiers below
+const (
+	tInt128  cFormatRune = 'o' // 128-bit integer -> *big.Int, like WithBigInt of a "16s"
+	tUInt128 cFormatRune = 'O' // unsigned 128-bit integer -> Uint128, like WithUint128 of a "16s"
+	tIntN    cFormatRune = 'v' // N-byte integer, N given by the count like for 's' -> *big.Int
+	tUIntN   cFormatRune = 'V' // unsigned N-byte integer -> *big.Int
+)
+
+var (
+	bigIntType  = reflect.TypeOf((*big.Int)(nil))
+	uint128Type = reflect.TypeOf(Uint128{})
+)
+
+// bigIntField is an 's' item of N bytes unpacked as an N-byte integer
+type bigIntField struct {
+	item      int
+	size      int
+	signed    bool // two's complement
+	uint128   bool // unpacked as Uint128 rather than *big.Int
+	bigEndian bool
+}
+
+// WithBigInt returns a copy of the struct whose item-th value, an 's' of N bytes, is an N-byte integer unpacked as *big.Int,
+// in two's complement if signed and in the byte order of the item, like "16s" for 128-bit integers or "3s" for 24-bit ones.
+// Pack accepts *big.Int, Uint128 and Go integers and rejects the values out of the range of N bytes.
+// Dump, ToJSON and ToCSV write the values in decimal, FromJSON and FromCSV accept them.
+// The format characters 'v' and 'V', like "3v" or "6V", declare the same signed and unsigned integers,
+// 'o' and 'O' the 128-bit ones of WithBigInt(item, true) and WithUint128(item).
+func (s *PyStruct) WithBigInt(item int, signed bool) (PyStruct, error) {
+	return s.withBigInt(item, signed, false)
+}
+
+// WithUint128 returns a copy of the struct whose item-th value, a "16s", is an unsigned 128-bit integer unpacked as Uint128.
+// Pack and the conversions work like with WithBigInt(item, false).
+func (s *PyStruct) WithUint128(item int) (PyStruct, error) {
+	return s.withBigInt(item, false, true)
+}
+
+func (s *PyStruct) withBigInt(item int, signed, uint128 bool) (PyStruct, error) {
+	if item < 0 || item >= s.items_num {
+		return PyStruct{}, fmt.Errorf("struct.error: item index %d out of range [0, %d)", item, s.items_num)
+	}
+	if err := s.checkNotSpecial(item); err != nil {
+		return PyStruct{}, err
+	}
+	it := s.item(item)
+	if it.format != tString || it.size == 0 {
+		return PyStruct{}, fmt.Errorf("struct.error: item %d (%s) is not a non-empty 's'", item, cFormatStringMap[it.format])
+	}
+	if uint128 && it.size != 16 {
+		return PyStruct{}, fmt.Errorf("struct.error: item %d of %d bytes can't hold a Uint128", item, it.size)
+	}
+
+	f := bigIntField{
+		item:      item,
+		size:      it.size,
+		signed:    signed,
+		uint128:   uint128,
+		bigEndian: s.groups[it.group].order == binary.ByteOrder(binary.BigEndian),
+	}
+	result := *s
+	result.bigints = append(append([]bigIntField(nil), s.bigints...), f)
+	return result, nil
+}
+
+func (s *PyStruct) bigInt(item int) (bigIntField, bool) {
+	for _, f := range s.bigints {
+		if f.item == item {
+			return f, true
+		}
+	}
+	return bigIntField{}, false
+}
+
+// typ returns the Go type of the values
+func (f bigIntField) typ() reflect.Type {
+	if f.uint128 {
+		return uint128Type
+	}
+	return bigIntType
+}
+
+// cType returns the name of the integer type, like "Int128" or "UInt24"
+func (f bigIntField) cType() string {
+	if f.signed {
+		return fmt.Sprintf("Int%d", 8*f.size)
+	}
+	return fmt.Sprintf("UInt%d", 8*f.size)
+}
+
+// swapBytes returns a copy of data converted between the byte order of the item and big-endian
+func (f bigIntField) swapBytes(data []byte) []byte {
+	be := make([]byte, len(data))
+	for i := range data {
+		if f.bigEndian {
+			be[i] = data[i]
+		} else {
+			be[len(data)-1-i] = data[i]
+		}
+	}
+	return be
+}
+
+// convert returns the decoded 's' value as the integer
+func (f bigIntField) convert(value interface{}) interface{} {
+	data := f.swapBytes([]byte(value.(string)))
+	if f.uint128 {
+		return Uint128{binary.BigEndian.Uint64(data[:8]), binary.BigEndian.Uint64(data[8:])}
+	}
+	n := new(big.Int).SetBytes(data)
+	if f.signed && data[0]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(8*f.size)))
+	}
+	return n
+}
+
+// bigValue returns the integer value as a big.Int
+func bigValue(value interface{}) (*big.Int, bool) {
+	switch v := value.(type) {
+	case *big.Int:
+		return v, v != nil
+	case Uint128:
+		return v.Big(), true
+	}
+	v := reflect.ValueOf(value)
+	switch {
+	case v.CanInt():
+		return big.NewInt(v.Int()), true
+	case v.CanUint():
+		return new(big.Int).SetUint64(v.Uint()), true
+	}
+	return nil, false
+}
+
+// rawValue returns the integer as the 's' value accepted by the packing
+func (f bigIntField) rawValue(value interface{}) (interface{}, error) {
+	n, ok := bigValue(value)
+	if !ok {
+		return nil, fmt.Errorf("struct.error: required argument of integer item %d is not an integer", f.item)
+	}
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(8*f.size)) // 2^(8N)
+	min, max := new(big.Int), new(big.Int).Sub(limit, big.NewInt(1))
+	if f.signed {
+		max.Rsh(limit, 1).Sub(max, big.NewInt(1))
+		min.Rsh(limit, 1).Neg(min)
+	}
+	if n.Cmp(min) < 0 || n.Cmp(max) > 0 {
+		return nil, fmt.Errorf("struct.error: %v is out of range of integer item %d", value, f.item)
+	}
+	if n.Sign() < 0 {
+		n = new(big.Int).Add(n, limit)
+	}
+	return string(f.swapBytes(n.FillBytes(make([]byte, f.size)))), nil
+}
+
+// parse parses the decimal text of the integer
+func (f bigIntField) parse(text string) (interface{}, error) {
+	n, ok := new(big.Int).SetString(text, 10)
+	if !ok {
+		return nil, fmt.Errorf("struct.error: %q is not an integer", text)
+	}
+	return n, nil
+}
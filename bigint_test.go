@@ -0,0 +1,197 @@
+package pystruct
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWithBigInt(t *testing.T) {
+	record, _ := NewStructWithNames(">3s6sH", "offset", "mac", "port")
+	s, err := record.WithBigInt(0, true) // int24
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, err = s.WithBigInt(1, false); err != nil { // uint48
+		t.Fatal(err)
+	}
+
+	buffer, err := s.Pack(-2, uint64(0x0a0b0c0d0e0f), uint16(80))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{0xff, 0xff, 0xfe, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x00, 0x50}
+	if !bytes.Equal(buffer, expected) {
+		t.Errorf("Expected: %v\nActual: %v\n", expected, buffer)
+	}
+	values, err := s.Unpack(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[0].(*big.Int).Int64() != -2 || values[1].(*big.Int).Uint64() != 0x0a0b0c0d0e0f || values[2] != uint16(80) {
+		t.Errorf("Unexpected values %v", values)
+	}
+
+	// int24 ranges over [-2^23, 2^23)
+	if _, err := s.Pack(1<<23, 0, uint16(0)); err == nil {
+		t.Error("Expected an out of range error for 2^23")
+	}
+	if _, err := s.Pack(-1<<23, 0, uint16(0)); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.Pack(0, -1, uint16(0)); err == nil {
+		t.Error("Expected an out of range error for a negative unsigned value")
+	}
+	if _, err := s.Pack(0, "1", uint16(0)); err == nil {
+		t.Error("Expected an error for a string value")
+	}
+	if _, err := record.WithBigInt(2, false); err == nil {
+		t.Error("Expected an error for an 'H' item")
+	}
+
+	data, err := s.ToJSON(buffer, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `[{"offset":-2,"mac":11042563100175,"port":80}]` {
+		t.Errorf("Unexpected JSON %s", data)
+	}
+	decoded, err := s.FromJSON(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, buffer) {
+		t.Errorf("Expected: %v\nActual: %v\n", buffer, decoded)
+	}
+
+	layout := s.Describe()
+	if field := layout.Fields[0]; field.Format != "s" || field.CType != "Int24" || field.GoType != "*big.Int" {
+		t.Errorf("Unexpected layout of the integer field %+v", field)
+	}
+	if field := layout.Fields[1]; field.CType != "UInt48" {
+		t.Errorf("Unexpected layout of the integer field %+v", field)
+	}
+
+	var dump strings.Builder
+	if err := s.Dump(&dump, buffer); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(dump.String(), "11042563100175") {
+		t.Errorf("Expected the decimal value in the dump:\n%s", dump.String())
+	}
+}
+
+func TestWithUint128(t *testing.T) {
+	id := Uint128{Hi: 0x0102030405060708, Lo: 0x090a0b0c0d0e0f10}
+	for _, format := range []string{"<16s", ">16s"} {
+		raw, _ := NewStruct(format)
+		s, err := raw.WithUint128(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buffer, err := s.Pack(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+		if format[0] == '<' {
+			for i, j := 0, len(expected)-1; i < j; i, j = i+1, j-1 {
+				expected[i], expected[j] = expected[j], expected[i]
+			}
+		}
+		if !bytes.Equal(buffer, expected) {
+			t.Errorf("%s: Expected: %v\nActual: %v\n", format, expected, buffer)
+		}
+		values, err := s.Unpack(buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if values[0] != id {
+			t.Errorf("%s: Expected %v, got %v", format, id, values[0])
+		}
+		if packed, _ := s.Pack(id.Big()); !bytes.Equal(packed, buffer) {
+			t.Errorf("%s: Expected: %v\nActual: %v\n", format, buffer, packed)
+		}
+	}
+
+	plain, _ := NewStruct("<16s")
+	if s, _ := plain.WithUint128(0); s.Describe().Fields[0].GoType != "pystruct.Uint128" {
+		t.Errorf("Unexpected layout %+v", s.Describe())
+	}
+
+	short, _ := NewStruct("8s")
+	if _, err := short.WithUint128(0); err == nil {
+		t.Error("Expected an error for an 8 bytes item")
+	}
+
+	type record struct {
+		ID    Uint128
+		Count uint32
+	}
+	raw, _ := NewStruct("<16sI")
+	s, _ := raw.WithUint128(0)
+	buffer, _ := s.Pack(id, uint32(3))
+	var records []record
+	if err := s.UnpackSlice(buffer, &records); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(records, []record{{id, 3}}) {
+		t.Errorf("Unexpected records %v", records)
+	}
+	type bytesRecord struct {
+		ID    [16]byte
+		Count uint32
+	}
+	var raws []bytesRecord
+	if err := s.UnpackSlice(buffer, &raws); err == nil {
+		t.Error("Expected an error for a byte array bound to a Uint128 item")
+	}
+}
+
+func TestIntegerFormats(t *testing.T) {
+	s, err := NewStructWithNames(">3v6VH", "offset", "mac", "port")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer, err := s.Pack(-2, uint64(0x0a0b0c0d0e0f), uint16(80))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{0xff, 0xff, 0xfe, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x00, 0x50}
+	if !bytes.Equal(buffer, expected) {
+		t.Errorf("Expected: %v\nActual: %v\n", expected, buffer)
+	}
+	values, err := s.Unpack(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[0].(*big.Int).Int64() != -2 || values[1].(*big.Int).Uint64() != 0x0a0b0c0d0e0f {
+		t.Errorf("Unexpected values %v", values)
+	}
+	if _, err := s.Pack(1<<23, 0, uint16(0)); err == nil {
+		t.Error("Expected an out of range error for 2^23")
+	}
+
+	// the package-level functions see the integers too
+	id := Uint128{Hi: 1, Lo: 2}
+	if size, err := CalcSize("<oO"); err != nil || size != 32 {
+		t.Errorf("Unexpected size %d, %v", size, err)
+	}
+	packed, err := Pack("<oO", -1, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values, err := Unpack("<oO", packed); err != nil || values[0].(*big.Int).Int64() != -1 || values[1] != id {
+		t.Errorf("Unexpected values %v, %v", values, err)
+	}
+
+	for _, format := range []string{"2o", "3O", "0v", "<0V"} {
+		var formatErr *FormatError
+		if _, err := NewStruct(format); !errors.As(err, &formatErr) {
+			t.Errorf("%q: Expected a format error, got %v", format, err)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package pystruct
+
+import (
+	"encoding"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// StructFormatter is implemented by Go struct types declaring their own format string,
+// it lets a zero Binary, like the one created by encoding/gob, find its layout
+type StructFormatter interface {
+	StructFormat() string
+}
+
+// Binary binds a value of the Go struct type T to a PyStruct layout
+// and implements encoding.BinaryMarshaler and encoding.BinaryUnmarshaler,
+// io.WriterTo and io.ReaderFrom on top of it.
+// The fields of T are bound to the format items like PackSlice does.
+// A Binary created without NewBinary uses the format returned by T's StructFormat method.
+type Binary[T any] struct {
+	Value T
+	s     *PyStruct
+}
+
+var (
+	_ encoding.BinaryMarshaler   = (*Binary[struct{}])(nil)
+	_ encoding.BinaryUnmarshaler = (*Binary[struct{}])(nil)
+	_ io.WriterTo                = (*Binary[struct{}])(nil)
+	_ io.ReaderFrom              = (*Binary[struct{}])(nil)
+)
+
+// NewBinary binds value to the layout of s
+func NewBinary[T any](s PyStruct, value T) *Binary[T] {
+	return &Binary[T]{Value: value, s: &s}
+}
+
+type bindingKey struct {
+	format     string
+	valueTypes string // scaled and N-byte integer items, which bind to other Go types than their format
+	typ        reflect.Type
+}
+
+// bindings caches the struct bindings of Binary values by format, value types and type
+var bindings sync.Map
+
+// binding returns the layout and the fields binding of the value
+func (b *Binary[T]) binding() (*PyStruct, *structBinding, error) {
+	if b.s == nil {
+		formatter, ok := interface{}(b.Value).(StructFormatter)
+		if !ok {
+			formatter, ok = interface{}(&b.Value).(StructFormatter)
+		}
+		if !ok {
+			return nil, nil, fmt.Errorf("struct.error: %T has no layout, use NewBinary or implement StructFormatter", b.Value)
+		}
+		s, err := compile(formatter.StructFormat())
+		if err != nil {
+			return nil, nil, err
+		}
+		b.s = &s
+	}
+
+	key := bindingKey{b.s.format, b.s.valueTypes(), reflect.TypeOf(&b.Value).Elem()}
+	if cached, ok := bindings.Load(key); ok {
+		return b.s, cached.(*structBinding), nil
+	}
+	binding, err := b.s.bindStruct(key.typ)
+	if err != nil {
+		return nil, nil, err
+	}
+	bindings.Store(key, binding)
+	return b.s, binding, nil
+}
+
+// AppendBinary appends the packed value to dst
+func (b *Binary[T]) AppendBinary(dst []byte) ([]byte, error) {
+	s, binding, err := b.binding()
+	if err != nil {
+		return dst, err
+	}
+	return s.AppendPack(dst, binding.values(reflect.ValueOf(&b.Value).Elem())...)
+}
+
+// MarshalBinary returns the packed value
+func (b *Binary[T]) MarshalBinary() ([]byte, error) {
+	return b.AppendBinary(nil)
+}
+
+// UnmarshalBinary unpacks data into the value,
+// the data size in bytes must match the size required by the format.
+// The fields bound to constants are set even if the struct omits the constant values.
+func (b *Binary[T]) UnmarshalBinary(data []byte) error {
+	s, binding, err := b.binding()
+	if err != nil {
+		return err
+	}
+	if len(data) != s.size {
+		return fmt.Errorf("struct.error: unpack requires a buffer of %d bytes", s.size)
+	}
+	if err := s.verify(data); err != nil {
+		return err
+	}
+	binding.set(reflect.ValueOf(&b.Value).Elem(), s.unpack(data))
+	return nil
+}
+
+// WriteTo writes the packed value to w
+func (b *Binary[T]) WriteTo(w io.Writer) (int64, error) {
+	data, err := b.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// ReadFrom reads exactly one packed value from r, unlike most io.ReaderFrom
+// implementations it doesn't read until EOF, so records can be read one after another
+func (b *Binary[T]) ReadFrom(r io.Reader) (int64, error) {
+	s, _, err := b.binding()
+	if err != nil {
+		return 0, err
+	}
+	data := make([]byte, s.size)
+	n, err := io.ReadFull(r, data)
+	if err != nil {
+		return int64(n), err
+	}
+	return int64(n), b.UnmarshalBinary(data)
+}
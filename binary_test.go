@@ -0,0 +1,184 @@
+package pystruct
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"testing"
+)
+
+type telemetry struct {
+	Kind   uint8
+	Length uint16
+	Delta  int32
+	Value  float64
+}
+
+func (telemetry) StructFormat() string {
+	return "<BHid"
+}
+
+func TestBinaryInterop(t *testing.T) {
+	value := telemetry{Kind: 1, Length: 513, Delta: -7, Value: 1.5}
+
+	var expected bytes.Buffer
+	if err := binary.Write(&expected, binary.LittleEndian, value); err != nil {
+		t.Fatal(err)
+	}
+
+	b := Binary[telemetry]{Value: value}
+	data, err := b.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, expected.Bytes()) {
+		t.Errorf("Expected: %v\nActual: %v\n", expected.Bytes(), data)
+	}
+
+	var read telemetry
+	if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, &read); err != nil {
+		t.Fatal(err)
+	}
+	if read != value {
+		t.Errorf("Expected: %+v\nActual: %+v\n", value, read)
+	}
+
+	var unmarshaled Binary[telemetry]
+	if err := unmarshaled.UnmarshalBinary(expected.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if unmarshaled.Value != value {
+		t.Errorf("Expected: %+v\nActual: %+v\n", value, unmarshaled.Value)
+	}
+
+	appended, err := b.AppendBinary([]byte{0xff})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(appended[1:], data) || appended[0] != 0xff {
+		t.Errorf("wrong appended data: %v", appended)
+	}
+}
+
+func TestBinaryGob(t *testing.T) {
+	value := Binary[telemetry]{Value: telemetry{Kind: 2, Length: 3, Delta: 4, Value: -5}}
+
+	var stream bytes.Buffer
+	if err := gob.NewEncoder(&stream).Encode(&value); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded Binary[telemetry]
+	if err := gob.NewDecoder(&stream).Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Value != value.Value {
+		t.Errorf("Expected: %+v\nActual: %+v\n", value.Value, decoded.Value)
+	}
+}
+
+func TestBinaryReadWrite(t *testing.T) {
+	type point struct {
+		X, Y int16
+	}
+	s, err := NewStruct(">2h")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stream bytes.Buffer
+	for _, p := range []point{{1, 2}, {-3, 4}} {
+		n, err := NewBinary(s, p).WriteTo(&stream)
+		if err != nil || n != 4 {
+			t.Fatalf("wrote %d bytes: %v", n, err)
+		}
+	}
+	if !bytes.Equal(stream.Bytes(), []byte{0, 1, 0, 2, 0xff, 0xfd, 0, 4}) {
+		t.Errorf("unexpected stream: %v", stream.Bytes())
+	}
+
+	b := NewBinary(s, point{})
+	for _, expected := range []point{{1, 2}, {-3, 4}} {
+		if _, err := b.ReadFrom(&stream); err != nil {
+			t.Fatal(err)
+		}
+		if b.Value != expected {
+			t.Errorf("Expected: %+v\nActual: %+v\n", expected, b.Value)
+		}
+	}
+	if _, err := b.ReadFrom(&stream); err == nil {
+		t.Error("expected error at the end of stream")
+	}
+
+	var unbound Binary[point]
+	if _, err := unbound.MarshalBinary(); err == nil {
+		t.Error("expected error for a value without layout")
+	}
+}
+
+func TestBinaryScaledBinding(t *testing.T) {
+	type plainReading struct {
+		Value uint16
+	}
+	type scaledReading struct {
+		Value float64
+	}
+	plain, _ := NewStruct("<H")
+	scaled, err := plain.WithScale(0, 0.01, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := NewBinary(plain, plainReading{500}).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, []byte{0xf4, 0x01}) {
+		t.Errorf("Expected: %v\nActual: %v\n", []byte{0xf4, 0x01}, data)
+	}
+	// the binding of the plain struct must not be reused for the scaled one
+	if _, err := NewBinary(scaled, plainReading{500}).MarshalBinary(); err == nil {
+		t.Error("Expected an error for an integer field bound to a scaled item")
+	}
+	data, err = NewBinary(scaled, scaledReading{5}).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, []byte{0xf4, 0x01}) {
+		t.Errorf("Expected: %v\nActual: %v\n", []byte{0xf4, 0x01}, data)
+	}
+	if _, err := NewBinary(plain, scaledReading{5}).MarshalBinary(); err == nil {
+		t.Error("Expected an error for a float field bound to an integer item")
+	}
+}
+
+func TestBinaryOmitConstants(t *testing.T) {
+	type header struct {
+		Magic string
+		Size  uint32
+	}
+	riff, _ := NewStruct("<4sI")
+	s, err := riff.WithConstant(0, "RIFF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	omitted := s.OmitConstants(true)
+
+	data, err := NewBinary(omitted, header{Size: 36}).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []byte("RIFF\x24\x00\x00\x00"); !bytes.Equal(data, expected) {
+		t.Errorf("Expected: %v\nActual: %v\n", expected, data)
+	}
+	b := NewBinary(omitted, header{})
+	if err := b.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if b.Value != (header{"RIFF", 36}) {
+		t.Errorf("Unexpected value %+v", b.Value)
+	}
+	if err := b.UnmarshalBinary([]byte("RIFX\x24\x00\x00\x00")); err == nil {
+		t.Error("Expected a constant error")
+	}
+}
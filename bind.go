@@ -0,0 +1,205 @@
+package pystruct
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// goTypeMap holds the Go types of the unpacked values
+var goTypeMap = map[cFormatRune]reflect.Type{
+	'c': reflect.TypeOf(rune(0)),
+	'b': reflect.TypeOf(int8(0)),
+	'B': reflect.TypeOf(uint8(0)),
+	'?': reflect.TypeOf(false),
+	'h': reflect.TypeOf(int16(0)),
+	'H': reflect.TypeOf(uint16(0)),
+	'i': reflect.TypeOf(int32(0)),
+	'I': reflect.TypeOf(uint32(0)),
+	'l': reflect.TypeOf(int32(0)),
+	'L': reflect.TypeOf(uint32(0)),
+	'q': reflect.TypeOf(int64(0)),
+	'Q': reflect.TypeOf(uint64(0)),
+	'f': reflect.TypeOf(float32(0)),
+	'd': reflect.TypeOf(float64(0)),
+	's': reflect.TypeOf(""),
+}
+
+// boundField is a Go struct field bound to one or more consecutive struct items
+type boundField struct {
+	index  []int
+	name   string
+	format cFormatRune
+	array  bool         // the field is an array consuming an item per element
+	typ    reflect.Type // Go type of the values of scaled or N-byte integer items, nil for the other items
+	count  int          // number of items consumed by the field
+}
+
+// structBinding maps the exported fields of a Go struct type to the items of a PyStruct.
+// Fields are bound in declaration order, a field tagged `pystruct:"-"` is skipped,
+// an array field consumes an item per element unless it is a byte array bound to 's'.
+type structBinding struct {
+	typ    reflect.Type
+	fields []boundField
+}
+
+// isByteSequence reports whether t is a []byte or [N]byte type
+func isByteSequence(t reflect.Type) bool {
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() == reflect.Uint8
+}
+
+// acceptsItem reports whether a field of type t can hold a value of the format
+func acceptsItem(t reflect.Type, format cFormatRune) bool {
+	if format == tString && isByteSequence(t) {
+		return true
+	}
+	return t.Kind() == goTypeMap[format].Kind()
+}
+
+// valueType returns the Go type of the values of a scaled or N-byte integer item, nil for the other items
+func (s *PyStruct) valueType(i int) reflect.Type {
+	if _, ok := s.scaled(i); ok {
+		return goTypeMap[tDouble]
+	}
+	if f, ok := s.bigInt(i); ok {
+		return f.typ()
+	}
+	return nil
+}
+
+// valueTypes describes the items whose Go types differ from the ones of their format,
+// structs of the same format and value types bind the same Go types
+func (s *PyStruct) valueTypes() string {
+	var sb strings.Builder
+	for _, sc := range s.scales {
+		fmt.Fprintf(&sb, "%d:%s;", sc.item, goTypeMap[tDouble])
+	}
+	for _, f := range s.bigints {
+		fmt.Fprintf(&sb, "%d:%s;", f.item, f.typ())
+	}
+	return sb.String()
+}
+
+// acceptsValue reports whether a field of type t can hold the values of a scaled or N-byte integer item of type typ
+func acceptsValue(t, typ reflect.Type) bool {
+	if typ.Kind() == reflect.Float64 {
+		return t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64
+	}
+	return t == typ
+}
+
+// bindStruct binds the Go struct type t to the items of the struct
+func (s *PyStruct) bindStruct(t reflect.Type) (*structBinding, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("struct.error: %s is not a struct type", t)
+	}
+
+	binding := &structBinding{typ: t}
+	next := 0
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" || field.Tag.Get("pystruct") == "-" {
+			continue
+		}
+		if next >= s.items_num {
+			return nil, fmt.Errorf("struct.error: %s has more fields than the format has items (%d)", t, s.items_num)
+		}
+
+		bound := boundField{index: field.Index, name: field.Name, format: s.item(next).format, typ: s.valueType(next), count: 1}
+		fieldType := field.Type
+		if field.Type.Kind() == reflect.Array && !(bound.format == tString && isByteSequence(field.Type)) {
+			bound.array = true
+			bound.count = field.Type.Len()
+			fieldType = field.Type.Elem()
+		}
+		if next+bound.count > s.items_num {
+			return nil, fmt.Errorf("struct.error: field %s.%s exceeds the format items", t, field.Name)
+		}
+		for j := next; j < next+bound.count; j++ {
+			if typ := s.valueType(j); typ != nil {
+				if typ != bound.typ || !acceptsValue(fieldType, typ) {
+					return nil, fmt.Errorf(
+						"struct.error: field %s.%s of type %s can't hold item %d (%s)",
+						t, field.Name, field.Type, j, typ,
+					)
+				}
+			} else if bound.typ != nil || !acceptsItem(fieldType, s.item(j).format) {
+				return nil, fmt.Errorf(
+					"struct.error: field %s.%s of type %s can't hold '%c' item %d (%s)",
+					t, field.Name, field.Type, s.item(j).format, j, goTypeMap[s.item(j).format],
+				)
+			}
+		}
+
+		binding.fields = append(binding.fields, bound)
+		next += bound.count
+	}
+
+	if next != s.items_num {
+		return nil, fmt.Errorf("struct.error: format requires %d items, %s has fields for %d", s.items_num, t, next)
+	}
+	return binding, nil
+}
+
+// itemValue converts the field value to the Go type of the format, or to typ for scaled and N-byte integer items
+func itemValue(v reflect.Value, format cFormatRune, typ reflect.Type) interface{} {
+	if typ != nil {
+		return v.Convert(typ).Interface()
+	}
+	if format == tString && isByteSequence(v.Type()) {
+		if v.Kind() == reflect.Array {
+			data := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(data), v)
+			return string(data)
+		}
+		return string(v.Bytes())
+	}
+	return v.Convert(goTypeMap[format]).Interface()
+}
+
+// setItemValue sets the field to the unpacked value
+func setItemValue(v reflect.Value, value interface{}) {
+	if str, ok := value.(string); ok && isByteSequence(v.Type()) {
+		if v.Kind() == reflect.Array {
+			reflect.Copy(v, reflect.ValueOf([]byte(str)))
+			return
+		}
+		v.SetBytes([]byte(str))
+		return
+	}
+	v.Set(reflect.ValueOf(value).Convert(v.Type()))
+}
+
+// values returns the item values of the struct value v in the packing order
+func (b *structBinding) values(v reflect.Value) []interface{} {
+	values := make([]interface{}, 0, len(b.fields))
+	for _, field := range b.fields {
+		fv := v.FieldByIndex(field.index)
+		if !field.array {
+			values = append(values, itemValue(fv, field.format, field.typ))
+			continue
+		}
+		for i := 0; i < field.count; i++ {
+			values = append(values, itemValue(fv.Index(i), field.format, field.typ))
+		}
+	}
+	return values
+}
+
+// set stores the unpacked values into the fields of the struct value v
+func (b *structBinding) set(v reflect.Value, values []interface{}) {
+	next := 0
+	for _, field := range b.fields {
+		fv := v.FieldByIndex(field.index)
+		if !field.array {
+			setItemValue(fv, values[next])
+			next++
+			continue
+		}
+		for i := 0; i < field.count; i++ {
+			setItemValue(fv.Index(i), values[next])
+			next++
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package pystruct
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultCacheSize is the default number of compiled formats
+// kept by the package-level functions, same as CPython's struct module does
+const DefaultCacheSize = 100
+
+// CacheStats holds the statistics of the compiled formats cache
+type CacheStats struct {
+	Hits    uint64 // number of lookups served from the cache
+	Misses  uint64 // number of lookups that required compiling the format
+	Len     int    // number of currently cached formats
+	MaxSize int    // maximum number of cached formats, 0 if the cache is disabled
+}
+
+type cacheEntry struct {
+	format string
+	s      PyStruct
+}
+
+// structCache is a concurrency-safe LRU cache of compiled PyStruct objects by format string
+type structCache struct {
+	mu      sync.Mutex
+	maxSize int
+	ll      *list.List
+	entries map[string]*list.Element
+	hits    uint64
+	misses  uint64
+}
+
+var formatCache = newStructCache(DefaultCacheSize)
+
+func newStructCache(maxSize int) *structCache {
+	return &structCache{
+		maxSize: maxSize,
+		ll:      list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *structCache) get(format string) (PyStruct, error) {
+	c.mu.Lock()
+	if elem, ok := c.entries[format]; ok {
+		c.ll.MoveToFront(elem)
+		c.hits++
+		s := elem.Value.(*cacheEntry).s
+		c.mu.Unlock()
+		return s, nil
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	// compile outside the lock, concurrent misses for the same format are harmless
+	s, err := NewStruct(format)
+	if err != nil {
+		return PyStruct{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.maxSize <= 0 {
+		return s, nil
+	}
+	if elem, ok := c.entries[format]; ok {
+		c.ll.MoveToFront(elem)
+		return s, nil
+	}
+	c.entries[format] = c.ll.PushFront(&cacheEntry{format: format, s: s})
+	c.evict()
+	return s, nil
+}
+
+// evict removes the least recently used entries exceeding maxSize, must be called with mu held
+func (c *structCache) evict() {
+	for c.ll.Len() > c.maxSize {
+		elem := c.ll.Back()
+		c.ll.Remove(elem)
+		delete(c.entries, elem.Value.(*cacheEntry).format)
+	}
+}
+
+// compile returns the compiled PyStruct for format, using the package cache
+func compile(format string) (PyStruct, error) {
+	return formatCache.get(format)
+}
+
+// SetCacheSize sets the maximum number of compiled formats kept by the package-level functions.
+// A size <= 0 disables the cache. Shrinking the cache evicts the least recently used formats.
+func SetCacheSize(size int) {
+	formatCache.mu.Lock()
+	defer formatCache.mu.Unlock()
+	if size < 0 {
+		size = 0
+	}
+	formatCache.maxSize = size
+	formatCache.evict()
+}
+
+// ClearCache removes all the compiled formats from the cache and resets its statistics
+func ClearCache() {
+	formatCache.mu.Lock()
+	defer formatCache.mu.Unlock()
+	formatCache.ll.Init()
+	formatCache.entries = make(map[string]*list.Element)
+	formatCache.hits = 0
+	formatCache.misses = 0
+}
+
+// GetCacheStats returns the current statistics of the compiled formats cache
+func GetCacheStats() CacheStats {
+	formatCache.mu.Lock()
+	defer formatCache.mu.Unlock()
+	return CacheStats{
+		Hits:    formatCache.hits,
+		Misses:  formatCache.misses,
+		Len:     formatCache.ll.Len(),
+		MaxSize: formatCache.maxSize,
+	}
+}
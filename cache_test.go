@@ -0,0 +1,85 @@
+package pystruct
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestCacheStats(t *testing.T) {
+	defer SetCacheSize(DefaultCacheSize)
+	SetCacheSize(DefaultCacheSize)
+	ClearCache()
+
+	for i := 0; i < 3; i++ {
+		if _, err := CalcSize("<3sf"); err != nil {
+			t.Fatal("Unbound error:", err)
+		}
+	}
+
+	stats := GetCacheStats()
+	if stats.Hits != 2 || stats.Misses != 1 || stats.Len != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	if _, err := CalcSize("<3z"); err == nil {
+		t.Error("expected error for bad format")
+	}
+	if stats = GetCacheStats(); stats.Len != 1 {
+		t.Errorf("bad format was cached: %+v", stats)
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	defer SetCacheSize(DefaultCacheSize)
+	SetCacheSize(2)
+	ClearCache()
+
+	for _, format := range []string{"<b", "<h", "<b", "<i"} {
+		if _, err := CalcSize(format); err != nil {
+			t.Fatal("Unbound error:", err)
+		}
+	}
+
+	// "<h" is the least recently used and must be evicted
+	if _, ok := formatCache.entries["<h"]; ok {
+		t.Error("least recently used format was not evicted")
+	}
+	if _, ok := formatCache.entries["<b"]; !ok {
+		t.Error("recently used format was evicted")
+	}
+
+	SetCacheSize(0)
+	if _, err := CalcSize("<q"); err != nil {
+		t.Fatal("Unbound error:", err)
+	}
+	if stats := GetCacheStats(); stats.Len != 0 || stats.MaxSize != 0 {
+		t.Errorf("disabled cache is not empty: %+v", stats)
+	}
+}
+
+func TestCacheConcurrent(t *testing.T) {
+	defer SetCacheSize(DefaultCacheSize)
+	SetCacheSize(4)
+	ClearCache()
+
+	formats := []string{"<b", "<h", "<i", "<q", "<f", "<d"}
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				format := formats[(g+i)%len(formats)]
+				if _, err := Unpack(format, make([]byte, formatAlignmentMap[cFormatRune(format[1])])); err != nil {
+					t.Error("Unbound error:", err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if stats := GetCacheStats(); stats.Hits+stats.Misses != 800 || stats.Len > 4 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
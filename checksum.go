@@ -0,0 +1,210 @@
+package pystruct
+
+import (
+	"fmt"
+	"hash/adler32"
+	"hash/crc32"
+	"reflect"
+)
+
+// Checksum is an algorithm computing a checksum field, see WithChecksum
+type Checksum int
+
+const (
+	CRC8        Checksum = iota + 1 // CRC-8/SMBUS, poly 0x07
+	CRC16CCITT                      // CRC-16/CCITT-FALSE, poly 0x1021, init 0xffff
+	CRC16Modbus                     // CRC-16/MODBUS, reflected poly 0x8005, init 0xffff
+	CRC32                           // CRC-32/ISO-HDLC as in zlib and Ethernet
+	CRC32C                          // CRC-32C (Castagnoli)
+	Sum8                            // sum of the bytes modulo 256
+	XOR8                            // xor of the bytes
+	Fletcher16                      // Fletcher-16, sums modulo 255
+	Adler32                         // Adler-32 as in zlib
+)
+
+var checksumNames = map[Checksum]string{
+	CRC8:        "CRC-8",
+	CRC16CCITT:  "CRC-16/CCITT",
+	CRC16Modbus: "CRC-16/MODBUS",
+	CRC32:       "CRC-32",
+	CRC32C:      "CRC-32C",
+	Sum8:        "sum8",
+	XOR8:        "XOR",
+	Fletcher16:  "Fletcher-16",
+	Adler32:     "Adler-32",
+}
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+func (c Checksum) String() string {
+	if name, ok := checksumNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("Checksum(%d)", int(c))
+}
+
+// Size returns the size of the checksum in bytes
+func (c Checksum) Size() int {
+	switch c {
+	case CRC8, Sum8, XOR8:
+		return 1
+	case CRC16CCITT, CRC16Modbus, Fletcher16:
+		return 2
+	case CRC32, CRC32C, Adler32:
+		return 4
+	}
+	return 0
+}
+
+// Compute returns the checksum of data
+func (c Checksum) Compute(data []byte) uint64 {
+	switch c {
+	case CRC8:
+		var crc uint8
+		for _, b := range data {
+			crc ^= b
+			for i := 0; i < 8; i++ {
+				if crc&0x80 != 0 {
+					crc = crc<<1 ^ 0x07
+				} else {
+					crc <<= 1
+				}
+			}
+		}
+		return uint64(crc)
+	case CRC16CCITT:
+		crc := uint16(0xffff)
+		for _, b := range data {
+			crc ^= uint16(b) << 8
+			for i := 0; i < 8; i++ {
+				if crc&0x8000 != 0 {
+					crc = crc<<1 ^ 0x1021
+				} else {
+					crc <<= 1
+				}
+			}
+		}
+		return uint64(crc)
+	case CRC16Modbus:
+		crc := uint16(0xffff)
+		for _, b := range data {
+			crc ^= uint16(b)
+			for i := 0; i < 8; i++ {
+				if crc&1 != 0 {
+					crc = crc>>1 ^ 0xa001
+				} else {
+					crc >>= 1
+				}
+			}
+		}
+		return uint64(crc)
+	case CRC32:
+		return uint64(crc32.ChecksumIEEE(data))
+	case CRC32C:
+		return uint64(crc32.Checksum(data, castagnoliTable))
+	case Sum8:
+		var sum uint8
+		for _, b := range data {
+			sum += b
+		}
+		return uint64(sum)
+	case XOR8:
+		var x uint8
+		for _, b := range data {
+			x ^= b
+		}
+		return uint64(x)
+	case Fletcher16:
+		var sum1, sum2 uint16
+		for _, b := range data {
+			sum1 = (sum1 + uint16(b)) % 255
+			sum2 = (sum2 + sum1) % 255
+		}
+		return uint64(sum2)<<8 | uint64(sum1)
+	case Adler32:
+		return uint64(adler32.Checksum(data))
+	}
+	return 0
+}
+
+// ChecksumError is returned when a checksum field doesn't match the checksum of its byte range
+type ChecksumError struct {
+	Item     int      // index of the checksum value, like in the result of Fields
+	Checksum Checksum // algorithm
+	Computed uint64   // checksum of the byte range
+	Stored   uint64   // value of the checksum field
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("struct.error: %s mismatch of item %d: computed %#x, stored %#x", e.Checksum, e.Item, e.Computed, e.Stored)
+}
+
+// checksumField is an item filled with the checksum of the bytes in [start, end) of the packed struct
+type checksumField struct {
+	item     int
+	checksum Checksum
+	start    int
+	end      int
+}
+
+// WithChecksum returns a copy of the struct whose item-th value is the checksum of its packed bytes in [start, end),
+// the item must be an unsigned integer large enough for the checksum and outside of the range.
+// Pack fills the checksum and accepts the values of all items, ignoring the checksum ones,
+// or the values of the other items only. Unpack verifies the checksum and returns a *ChecksumError on mismatch.
+// Checksums are computed in the order they are added, so a checksum may cover a previous one.
+func (s *PyStruct) WithChecksum(item int, checksum Checksum, start, end int) (PyStruct, error) {
+	if item < 0 || item >= s.items_num {
+		return PyStruct{}, fmt.Errorf("struct.error: item index %d out of range [0, %d)", item, s.items_num)
+	}
+	if checksum.Size() == 0 {
+		return PyStruct{}, fmt.Errorf("struct.error: unknown checksum %v", checksum)
+	}
+	it := s.item(item)
+	if kind := goTypeMap[it.format].Kind(); kind < reflect.Uint8 || kind > reflect.Uint64 || it.size < checksum.Size() {
+		return PyStruct{}, fmt.Errorf("struct.error: %s requires an unsigned integer of at least %d bytes, item %d is %s",
+			checksum, checksum.Size(), item, cFormatStringMap[it.format])
+	}
+	if start < 0 || start > end || end > s.size {
+		return PyStruct{}, fmt.Errorf("struct.error: checksum range [%d, %d) out of range [0, %d)", start, end, s.size)
+	}
+	if start < it.offset+it.size && it.offset < end {
+		return PyStruct{}, fmt.Errorf("struct.error: checksum range [%d, %d) overlaps the checksum item %d", start, end, item)
+	}
+	if err := s.checkNotSpecial(item); err != nil {
+		return PyStruct{}, err
+	}
+
+	result := *s
+	result.checksums = append(append([]checksumField(nil), s.checksums...), checksumField{item, checksum, start, end})
+	return result, nil
+}
+
+func (s *PyStruct) isChecksum(item int) bool {
+	for _, c := range s.checksums {
+		if c.item == item {
+			return true
+		}
+	}
+	return false
+}
+
+// fillChecksums writes the checksums into the packed struct
+func (s *PyStruct) fillChecksums(buffer []byte) {
+	for _, c := range s.checksums {
+		it := s.item(c.item)
+		value := reflect.ValueOf(c.checksum.Compute(buffer[c.start:c.end])).Convert(goTypeMap[it.format])
+		s.encodeItem(buffer, it, value.Interface())
+	}
+}
+
+// verifyChecksums checks the checksums of the packed struct
+func (s *PyStruct) verifyChecksums(buffer []byte) error {
+	for _, c := range s.checksums {
+		it := s.item(c.item)
+		stored := reflect.ValueOf(s.decodeItem(buffer, it)).Uint()
+		if computed := c.checksum.Compute(buffer[c.start:c.end]); computed != stored {
+			return &ChecksumError{c.item, c.checksum, computed, stored}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package pystruct
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestChecksumCompute(t *testing.T) {
+	check := []byte("123456789")
+	expected := map[Checksum]uint64{
+		CRC8:        0xf4,
+		CRC16CCITT:  0x29b1,
+		CRC16Modbus: 0x4b37,
+		CRC32:       0xcbf43926,
+		CRC32C:      0xe3069283,
+		Sum8:        0xdd,
+		XOR8:        0x31,
+		Fletcher16:  0x1ede,
+		Adler32:     0x091e01de,
+	}
+	for checksum, value := range expected {
+		if computed := checksum.Compute(check); computed != value {
+			t.Errorf("%s expected: %#x, actual: %#x", checksum, value, computed)
+		}
+	}
+}
+
+func TestWithChecksum(t *testing.T) {
+	record, _ := NewStruct("<BHI")
+	s, err := record.WithChecksum(2, CRC32, 0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buffer, err := s.Pack(uint8(1), uint16(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	crc := CRC32.Compute(buffer[:3])
+	values, err := s.Unpack(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, []interface{}{uint8(1), uint16(2), uint32(crc)}) {
+		t.Errorf("Unexpected values %v", values)
+	}
+
+	// the values of all items are accepted, the checksum is recomputed
+	repacked, err := s.Pack(uint8(1), uint16(2), uint32(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(repacked, buffer) {
+		t.Errorf("Expected: %v\nActual: %v\n", buffer, repacked)
+	}
+	if _, err := s.Pack(uint8(1)); err == nil {
+		t.Error("Expected error for missing values")
+	}
+
+	buffer[1] ^= 0xff
+	var mismatch *ChecksumError
+	if _, err := s.Unpack(buffer); !errors.As(err, &mismatch) || mismatch.Item != 2 || mismatch.Stored != crc {
+		t.Errorf("Expected checksum error, got %v", err)
+	}
+	if _, err := s.UnpackFrom(append(buffer, 0), 0); !errors.As(err, &mismatch) {
+		t.Errorf("Expected checksum error, got %v", err)
+	}
+	if _, err := s.UnpackArray(append(repacked, buffer...)); !errors.As(err, &mismatch) {
+		t.Errorf("Expected checksum error, got %v", err)
+	}
+
+	v, _ := NewView(s, buffer)
+	if err := v.Set(1, uint16(2)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Unpack(buffer); err != nil {
+		t.Errorf("Expected the view to update the checksum, got %v", err)
+	}
+}
+
+func TestWithChecksumErrors(t *testing.T) {
+	record, _ := NewStruct("<BHbI")
+	for _, c := range []struct {
+		item, start, end int
+		checksum         Checksum
+	}{
+		{4, 0, 1, CRC8},     // item out of range
+		{1, 0, 1, CRC32},    // item too small
+		{2, 0, 1, XOR8},     // signed item
+		{3, 0, 9, CRC32},    // range out of the struct
+		{3, 2, 8, CRC32},    // range covering the checksum
+		{3, 0, 4, 0},        // unknown checksum
+		{0, 2, 1, Sum8},     // inverted range
+		{3, -1, 3, Adler32}, // negative start
+	} {
+		if _, err := record.WithChecksum(c.item, c.checksum, c.start, c.end); err == nil {
+			t.Errorf("Expected error for %+v", c)
+		}
+	}
+
+	s, err := record.WithChecksum(0, Sum8, 1, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.WithChecksum(0, XOR8, 1, 4); err == nil {
+		t.Error("Expected error for an item already a checksum")
+	}
+	if _, err := Concat(s, record); err == nil {
+		t.Error("Expected error for composing a struct with checksums")
+	}
+}
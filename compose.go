@@ -0,0 +1,280 @@
+package pystruct
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// nestedStruct is a struct embedded in another one, spanning the format groups first to last
+type nestedStruct struct {
+	first     int
+	last      int
+	alignment int // alignment of the start and size of the nested struct, 1 unless it uses native alignment
+}
+
+// Concat returns a struct packing the values of the structs one after another,
+// with the layout a single format joining their formats would have:
+// the groups of native alignment are aligned relative to the start of the result.
+// Every struct keeps its byte order, if they differ the Format of the result can't be compiled by NewStruct.
+// Constant, enum, scaled and integer fields are kept, structs with checksums can't be composed,
+// nor structs with constants that don't agree on OmitConstants.
+func Concat(structs ...PyStruct) (PyStruct, error) {
+	for _, s := range structs {
+		if len(s.checksums) > 0 {
+			return PyStruct{}, fmt.Errorf("struct.error: structs with checksums can't be composed")
+		}
+	}
+	var result PyStruct
+	var err error
+	if result.omitConst, err = omitConstants(structs...); err != nil {
+		return PyStruct{}, err
+	}
+	named := false
+	items := 0
+	for _, s := range structs {
+		result.nested = append(result.nested, shiftNested(s.nested, 0, len(result.groups))...)
+		result.constants = append(result.constants, shiftConstants(s.constants, 0, items)...)
+		result.enums = append(result.enums, shiftEnums(s.enums, 0, items)...)
+		result.scales = append(result.scales, shiftScales(s.scales, 0, items)...)
+		result.bigints = append(result.bigints, shiftBigInts(s.bigints, 0, items)...)
+		result.groups = append(result.groups, s.groups...)
+		named = named || s.names != nil
+		items += s.items_num
+	}
+	if named {
+		for _, s := range structs {
+			for i := range s.groups {
+				result.names = append(result.names, s.fieldName(i))
+			}
+		}
+	}
+	if err := result.compose(); err != nil {
+		return PyStruct{}, err
+	}
+	return result, nil
+}
+
+// Embed returns a struct with inner nested before the field-th format group of outer,
+// or after its last group if field is the number of groups.
+// Inner is packed as a unit keeping its byte order, like a member of a C struct:
+// with native alignment its start is aligned and its size is padded to a multiple of its largest alignment.
+// The Format of the result shows inner in parentheses and can't be compiled by NewStruct.
+// Constant, enum, scaled and integer fields are kept, structs with checksums can't be composed,
+// nor structs with constants that don't agree on OmitConstants.
+func Embed(outer PyStruct, field int, inner PyStruct) (PyStruct, error) {
+	if field < 0 || field > len(outer.groups) {
+		return PyStruct{}, fmt.Errorf("struct.error: field index %d out of range [0, %d]", field, len(outer.groups))
+	}
+	if len(outer.checksums) > 0 || len(inner.checksums) > 0 {
+		return PyStruct{}, fmt.Errorf("struct.error: structs with checksums can't be composed")
+	}
+
+	var result PyStruct
+	result.groups = make([]formatGroup, 0, len(outer.groups)+len(inner.groups))
+	result.groups = append(result.groups, outer.groups[:field]...)
+	result.groups = append(result.groups, inner.groups...)
+	result.groups = append(result.groups, outer.groups[field:]...)
+
+	at := outer.items_num // index of the first item of the field
+	if field < len(outer.groups) {
+		at = outer.groups[field].first
+	}
+	result.constants = shiftConstants(outer.constants, at, inner.items_num)
+	result.constants = append(result.constants, shiftConstants(inner.constants, 0, at)...)
+	result.enums = shiftEnums(outer.enums, at, inner.items_num)
+	result.enums = append(result.enums, shiftEnums(inner.enums, 0, at)...)
+	result.scales = shiftScales(outer.scales, at, inner.items_num)
+	result.scales = append(result.scales, shiftScales(inner.scales, 0, at)...)
+	result.bigints = shiftBigInts(outer.bigints, at, inner.items_num)
+	result.bigints = append(result.bigints, shiftBigInts(inner.bigints, 0, at)...)
+	var err error
+	if result.omitConst, err = omitConstants(outer, inner); err != nil {
+		return PyStruct{}, err
+	}
+
+	result.nested = shiftNested(outer.nested, field, len(inner.groups))
+	result.nested = append(result.nested, shiftNested(inner.nested, 0, field)...)
+	if len(inner.groups) > 0 {
+		alignment := 1
+		for _, group := range inner.groups {
+			if align := groupAlignment(group); align > alignment {
+				alignment = align
+			}
+		}
+		result.nested = append(result.nested, nestedStruct{field, field + len(inner.groups) - 1, alignment})
+	}
+
+	if outer.names != nil || inner.names != nil {
+		result.names = make([]string, 0, len(result.groups))
+		for i := 0; i < field; i++ {
+			result.names = append(result.names, outer.fieldName(i))
+		}
+		for i := range inner.groups {
+			result.names = append(result.names, inner.fieldName(i))
+		}
+		for i := field; i < len(outer.groups); i++ {
+			result.names = append(result.names, outer.fieldName(i))
+		}
+	}
+
+	if err := result.compose(); err != nil {
+		return PyStruct{}, err
+	}
+	return result, nil
+}
+
+// omitConstants returns the OmitConstants setting of the composed struct,
+// the structs with constants must agree on it
+func omitConstants(structs ...PyStruct) (bool, error) {
+	omit, set := false, false
+	for _, s := range structs {
+		if len(s.constants) == 0 {
+			continue
+		}
+		if set && s.omitConst != omit {
+			return false, fmt.Errorf("struct.error: structs omitting their constants can't be composed with structs keeping them")
+		}
+		omit, set = s.omitConst, true
+	}
+	return omit, nil
+}
+
+// shiftNested returns a copy of the nested structs with the group indexes from at moved by n
+func shiftNested(nested []nestedStruct, at, n int) []nestedStruct {
+	shifted := make([]nestedStruct, 0, len(nested))
+	for _, ns := range nested {
+		if ns.first >= at {
+			ns.first += n
+		}
+		if ns.last >= at {
+			ns.last += n
+		}
+		shifted = append(shifted, ns)
+	}
+	return shifted
+}
+
+// shiftConstants returns a copy of the constants with the item indexes from at moved by n
+func shiftConstants(constants []constantField, at, n int) []constantField {
+	shifted := make([]constantField, 0, len(constants))
+	for _, c := range constants {
+		if c.item >= at {
+			c.item += n
+		}
+		shifted = append(shifted, c)
+	}
+	return shifted
+}
+
+// shiftEnums returns a copy of the enums with the item indexes from at moved by n
+func shiftEnums(enums []enumField, at, n int) []enumField {
+	shifted := make([]enumField, 0, len(enums))
+	for _, e := range enums {
+		if e.item >= at {
+			e.item += n
+		}
+		shifted = append(shifted, e)
+	}
+	return shifted
+}
+
+// shiftScales returns a copy of the scaled fields with the item indexes from at moved by n
+func shiftScales(scales []scaledField, at, n int) []scaledField {
+	shifted := make([]scaledField, 0, len(scales))
+	for _, sc := range scales {
+		if sc.item >= at {
+			sc.item += n
+		}
+		shifted = append(shifted, sc)
+	}
+	return shifted
+}
+
+// shiftBigInts returns a copy of the integer fields with the item indexes from at moved by n
+func shiftBigInts(bigints []bigIntField, at, n int) []bigIntField {
+	shifted := make([]bigIntField, 0, len(bigints))
+	for _, f := range bigints {
+		if f.item >= at {
+			f.item += n
+		}
+		shifted = append(shifted, f)
+	}
+	return shifted
+}
+
+// compose completes a struct built from the groups of other structs
+func (s *PyStruct) compose() error {
+	size, items_num, err := layoutGroups(s.groups, s.nested)
+	if err != nil {
+		return err
+	}
+	s.size = size
+	s.items_num = items_num
+
+	seen := make(map[string]bool, len(s.names))
+	for _, name := range s.names {
+		if name != "" && seen[name] {
+			return fmt.Errorf("struct.error: duplicate field name %q", name)
+		}
+		seen[name] = true
+	}
+
+	s.order = getNativeOrder()
+	for i, group := range s.groups {
+		if i == 0 {
+			s.order = group.order
+		} else if group.order != s.order {
+			s.order = nil
+			break
+		}
+	}
+	s.format = s.composedFormat()
+	s.table = &itemTable{}
+	return nil
+}
+
+// composedFormat returns the format of a composed struct, the byte order character is repeated
+// where it changes and nested structs are enclosed in parentheses with their own byte order character
+func (s *PyStruct) composedFormat() string {
+	var sb strings.Builder
+	var orders []cOrder
+	for i, group := range s.groups {
+		if i == 0 {
+			orders = append(orders, group.orderChar)
+			sb.WriteRune(rune(group.orderChar))
+		}
+
+		var opened []nestedStruct
+		for _, n := range s.nested {
+			if n.first == i {
+				opened = append(opened, n)
+			}
+		}
+		// the outer struct is opened first
+		sort.Slice(opened, func(a, b int) bool { return opened[a].last > opened[b].last })
+		for range opened {
+			orders = append(orders, group.orderChar)
+			sb.WriteByte('(')
+			sb.WriteRune(rune(group.orderChar))
+		}
+
+		if group.orderChar != orders[len(orders)-1] {
+			orders[len(orders)-1] = group.orderChar
+			sb.WriteRune(rune(group.orderChar))
+		}
+		if group.number != 1 {
+			sb.WriteString(strconv.Itoa(group.number))
+		}
+		sb.WriteRune(rune(group.format))
+
+		for _, n := range s.nested {
+			if n.last == i {
+				orders = orders[:len(orders)-1]
+				sb.WriteByte(')')
+			}
+		}
+	}
+	return sb.String()
+}
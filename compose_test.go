@@ -0,0 +1,144 @@
+package pystruct
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func offsetsOf(s PyStruct) []int {
+	var offsets []int
+	for _, field := range s.Fields() {
+		offsets = append(offsets, field.Offset)
+	}
+	return offsets
+}
+
+func TestConcat(t *testing.T) {
+	for _, formats := range [][2]string{{"@b", "@i"}, {"bh", "3sd"}, {"<H", "<iq"}, {"", "@?c"}} {
+		a, _ := NewStruct(formats[0])
+		b, _ := NewStruct(formats[1])
+		s, err := Concat(a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		joined, err := NewStruct(s.Format())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Size() != joined.Size() || !reflect.DeepEqual(s.Fields(), joined.Fields()) {
+			t.Errorf("%q + %q: expected layout of %q, got %+v", formats[0], formats[1], s.Format(), s.Fields())
+		}
+	}
+
+	header, _ := NewStructWithNames("<HB", "length", "kind")
+	body, _ := NewStructWithNames(">I", "value")
+	s, err := Concat(header, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Format() != "<HB>I" || s.Size() != 7 || s.ByteOrder() != nil {
+		t.Errorf("Unexpected struct %q of %d bytes, byte order %v", s.Format(), s.Size(), s.ByteOrder())
+	}
+	if !reflect.DeepEqual(s.Names(), []string{"length", "kind", "value"}) {
+		t.Errorf("Unexpected names %v", s.Names())
+	}
+
+	buffer, err := s.Pack(uint16(1), uint8(2), uint32(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []byte{1, 0, 2, 0, 0, 0, 3}; !bytes.Equal(buffer, expected) {
+		t.Errorf("Expected: %v\nActual: %v\n", expected, buffer)
+	}
+	values, err := s.Unpack(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, []interface{}{uint16(1), uint8(2), uint32(3)}) {
+		t.Errorf("Unexpected values %v", values)
+	}
+
+	layout := s.Describe()
+	if layout.ByteOrder != "<" || layout.Fields[1].ByteOrder != "" || layout.Fields[2].ByteOrder != ">" {
+		t.Errorf("Unexpected layout byte orders %+v", layout)
+	}
+	if _, err := NewStructFromLayout(layout); err == nil {
+		t.Error("Expected error for a layout of mixed byte orders")
+	}
+
+	if _, err := Concat(header, header); err == nil {
+		t.Error("Expected error for duplicate names")
+	}
+}
+
+func TestEmbed(t *testing.T) {
+	outer, _ := NewStruct("@bd")
+	inner, _ := NewStruct("@ib")
+
+	// struct { char; struct { int; char; }; double; }
+	s, err := Embed(outer, 1, inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Format() != "@b(@ib)d" || s.Size() != 24 {
+		t.Errorf("Unexpected struct %q of %d bytes", s.Format(), s.Size())
+	}
+	if offsets := offsetsOf(s); !reflect.DeepEqual(offsets, []int{0, 4, 8, 16}) {
+		t.Errorf("Unexpected offsets %v", offsets)
+	}
+
+	values := []interface{}{int8(1), int32(-2), int8(3), 4.5}
+	buffer, err := s.Pack(values...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buffer) != s.Size() {
+		t.Errorf("Expected %d packed bytes, got %d", s.Size(), len(buffer))
+	}
+	unpacked, err := s.Unpack(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(unpacked, values) {
+		t.Errorf("Expected: %v\nActual: %v\n", values, unpacked)
+	}
+
+	// the trailing padding of the nested struct is kept at the end and when concatenated
+	last, err := Embed(outer, 2, inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offsets := offsetsOf(last); last.Size() != 24 || !reflect.DeepEqual(offsets, []int{0, 8, 16, 20}) {
+		t.Errorf("Unexpected struct of %d bytes, offsets %v", last.Size(), offsets)
+	}
+	if buffer, err := last.Pack(int8(1), 2.5, int32(3), int8(4)); err != nil || len(buffer) != 24 {
+		t.Errorf("Expected 24 packed bytes, got %d, %v", len(buffer), err)
+	}
+	tail, _ := NewStruct("b")
+	joined, err := Concat(last, tail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if joined.Format() != "@bd(@ib)b" || joined.Size() != 25 || offsetsOf(joined)[4] != 24 {
+		t.Errorf("Unexpected struct %q of %d bytes, offsets %v", joined.Format(), joined.Size(), offsetsOf(joined))
+	}
+
+	// a nested struct of standard alignment keeps its byte order and isn't aligned
+	be, _ := NewStruct(">I")
+	mixed, err := Embed(tail, 1, be)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer, err = mixed.Pack(int8(1), uint32(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []byte{1, 0, 0, 0, 2}; mixed.Format() != "@b(>I)" || !bytes.Equal(buffer, expected) {
+		t.Errorf("%q expected: %v\nActual: %v\n", mixed.Format(), expected, buffer)
+	}
+
+	if _, err := Embed(outer, 3, inner); err == nil {
+		t.Error("Expected error for field index out of range")
+	}
+}
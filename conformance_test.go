@@ -0,0 +1,258 @@
+package pystruct
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// conformanceVector is a test vector generated with CPython's struct module
+// by testdata/gen_conformance.py
+type conformanceVector struct {
+	Format   string        `json:"format"`
+	Size     int           `json:"size"`
+	Values   []interface{} `json:"values"`
+	Packed   string        `json:"packed"`
+	Unpacked []interface{} `json:"unpacked"`
+}
+
+type conformanceCorpus struct {
+	Python    string              `json:"python"`
+	ByteOrder string              `json:"byteorder"`
+	Vectors   []conformanceVector `json:"vectors"`
+}
+
+func loadConformanceCorpus(t *testing.T) conformanceCorpus {
+	file, err := os.Open("testdata/conformance.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var corpus conformanceCorpus
+	decoder := json.NewDecoder(file)
+	decoder.UseNumber()
+	if err := decoder.Decode(&corpus); err != nil {
+		t.Fatal(err)
+	}
+	return corpus
+}
+
+// itemFormats returns the format character of each value packed by s
+func itemFormats(s PyStruct) []cFormatRune {
+	var formats []cFormatRune
+	for _, group := range s.groups {
+		if group.format == tString {
+			formats = append(formats, group.format)
+			continue
+		}
+		for num := 0; num < group.number; num++ {
+			formats = append(formats, group.format)
+		}
+	}
+	return formats
+}
+
+// parsePythonFloat parses a float repr, NaN is returned with the same bits CPython uses
+func parsePythonFloat(value interface{}) (float64, error) {
+	str, ok := value.(string)
+	if !ok {
+		return 0, fmt.Errorf("float expected as string, got %T", value)
+	}
+	if str == "nan" {
+		return math.Float64frombits(0x7ff8000000000000), nil
+	}
+	return strconv.ParseFloat(str, 64)
+}
+
+// conformanceValue converts a JSON encoded value to the Go type used by the format
+func conformanceValue(value interface{}, format cFormatRune) (interface{}, error) {
+	switch format {
+	case tChar, tString:
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("bytes expected as hex string, got %T", value)
+		}
+		data, err := hex.DecodeString(str)
+		if err != nil {
+			return nil, err
+		}
+		if format == tChar {
+			return rune(data[0]), nil
+		}
+		return string(data), nil
+	case tBool:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("bool expected, got %T", value)
+		}
+		return b, nil
+	case tFloat32:
+		f, err := parsePythonFloat(value)
+		return float32(f), err
+	case tDouble:
+		return parsePythonFloat(value)
+	}
+
+	number, ok := value.(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("integer expected, got %T", value)
+	}
+	switch format {
+	case tUChar, tUShort, tUInt, tULong, tULongLong:
+		n, err := strconv.ParseUint(number.String(), 10, formatAlignmentMap[format]*8)
+		if err != nil {
+			return nil, err
+		}
+		switch format {
+		case tUChar:
+			return uint8(n), nil
+		case tUShort:
+			return uint16(n), nil
+		case tULongLong:
+			return n, nil
+		default:
+			return uint32(n), nil
+		}
+	default:
+		n, err := strconv.ParseInt(number.String(), 10, formatAlignmentMap[format]*8)
+		if err != nil {
+			return nil, err
+		}
+		switch format {
+		case tSChar:
+			return int8(n), nil
+		case tShort:
+			return int16(n), nil
+		case tLongLong:
+			return n, nil
+		default:
+			return int32(n), nil
+		}
+	}
+}
+
+func conformanceValues(values []interface{}, formats []cFormatRune) ([]interface{}, error) {
+	if len(values) != len(formats) {
+		return nil, fmt.Errorf("expected %d values, got %d", len(formats), len(values))
+	}
+	converted := make([]interface{}, len(values))
+	for i, value := range values {
+		v, err := conformanceValue(value, formats[i])
+		if err != nil {
+			return nil, fmt.Errorf("value %d: %w", i, err)
+		}
+		converted[i] = v
+	}
+	return converted, nil
+}
+
+// sameValue compares values, floats are compared bitwise to distinguish -0.0 and NaN
+func sameValue(a, b interface{}) bool {
+	switch x := a.(type) {
+	case float32:
+		y, ok := b.(float32)
+		return ok && math.Float32bits(x) == math.Float32bits(y)
+	case float64:
+		y, ok := b.(float64)
+		return ok && math.Float64bits(x) == math.Float64bits(y)
+	}
+	return a == b
+}
+
+func TestConformance(t *testing.T) {
+	corpus := loadConformanceCorpus(t)
+	if len(corpus.Vectors) == 0 {
+		t.Fatal("empty conformance corpus")
+	}
+
+	nativeMatches := (corpus.ByteOrder == "little") == (getNativeOrder() == binary.LittleEndian)
+
+	for _, vector := range corpus.Vectors {
+		if !nativeMatches && !isStandardOrder(vector.Format) {
+			continue
+		}
+
+		if reason := knownDivergence(vector); reason != "" {
+			t.Run(vector.Format, func(t *testing.T) { t.Skip(reason) })
+			continue
+		}
+
+		s, err := NewStruct(vector.Format)
+		if err != nil {
+			t.Errorf("%q: %s", vector.Format, err)
+			continue
+		}
+
+		size, err := CalcSize(vector.Format)
+		if err != nil || size != vector.Size || s.Size() != vector.Size {
+			t.Errorf("%q: size expected %d, got %d (%v)", vector.Format, vector.Size, size, err)
+			continue
+		}
+
+		formats := itemFormats(s)
+		values, err := conformanceValues(vector.Values, formats)
+		if err != nil {
+			t.Errorf("%q: bad vector: %s", vector.Format, err)
+			continue
+		}
+		expected, err := conformanceValues(vector.Unpacked, formats)
+		if err != nil {
+			t.Errorf("%q: bad vector: %s", vector.Format, err)
+			continue
+		}
+		packed, err := hex.DecodeString(vector.Packed)
+		if err != nil {
+			t.Errorf("%q: bad vector: %s", vector.Format, err)
+			continue
+		}
+
+		actual, err := s.Pack(values...)
+		if err != nil {
+			t.Errorf("%q: pack %v: %s", vector.Format, values, err)
+		} else if !bytes.Equal(actual, packed) {
+			t.Errorf("%q: pack %v: expected %x, got %x", vector.Format, values, packed, actual)
+		}
+
+		unpacked, err := s.Unpack(packed)
+		if err != nil {
+			t.Errorf("%q: unpack %x: %s", vector.Format, packed, err)
+			continue
+		}
+		if len(unpacked) != len(expected) {
+			t.Errorf("%q: unpack %x: expected %v, got %v", vector.Format, packed, expected, unpacked)
+			continue
+		}
+		for i := range expected {
+			if !sameValue(unpacked[i], expected[i]) {
+				t.Errorf("%q: unpack %x: item %d expected %#v, got %#v", vector.Format, packed, i, expected[i], unpacked[i])
+			}
+		}
+	}
+}
+
+// knownDivergence returns why pystruct is known to differ from CPython on the vector, or an empty string
+func knownDivergence(vector conformanceVector) string {
+	if getOrderChar(vector.Format) == tNativeOrderSize && strings.ContainsAny(vector.Format, "lL") {
+		if size, err := CalcSize(vector.Format); err == nil && size != vector.Size {
+			return fmt.Sprintf("native 'l' and 'L' are C longs in CPython (size %d), pystruct packs them as 4 bytes (size %d)", vector.Size, size)
+		}
+	}
+	return ""
+}
+
+// isStandardOrder reports whether the format uses a fixed byte order independent of the platform
+func isStandardOrder(format string) bool {
+	if len(format) == 0 {
+		return false
+	}
+	ord, ok := cOrderMap[rune(format[0])]
+	return ok && ord != tNativeOrderSize && ord != tNativeOrder
+}
@@ -0,0 +1,125 @@
+package pystruct
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// ConstantError is returned when a constant field doesn't hold its value
+type ConstantError struct {
+	Item     int         // index of the constant value, like in the result of Fields
+	Expected interface{} // value of the constant
+	Actual   interface{} // unpacked value, or the value given to View.Set
+}
+
+func (e *ConstantError) Error() string {
+	return fmt.Sprintf("struct.error: item %d is %#v, expected constant %#v", e.Item, e.Actual, e.Expected)
+}
+
+// constantField is an item holding a constant value, like a magic number
+type constantField struct {
+	item  int
+	value interface{} // the value as unpacked
+	data  []byte      // the packed value
+}
+
+// WithConstant returns a copy of the struct whose item-th value is the constant value, like a magic number or a version.
+// Pack fills the constant and accepts the values of all items, ignoring the constant ones,
+// or the values of the other items only. Unpack verifies the constant and returns a *ConstantError on mismatch.
+func (s *PyStruct) WithConstant(item int, value interface{}) (PyStruct, error) {
+	if item < 0 || item >= s.items_num {
+		return PyStruct{}, fmt.Errorf("struct.error: item index %d out of range [0, %d)", item, s.items_num)
+	}
+	if err := s.checkNotSpecial(item); err != nil {
+		return PyStruct{}, err
+	}
+	it := s.item(item)
+	scratch := make([]byte, s.size)
+	if err := s.encodeItem(scratch, it, value); err != nil {
+		return PyStruct{}, err
+	}
+
+	result := *s
+	constant := constantField{item, s.decodeItem(scratch, it), append([]byte(nil), scratch[it.offset:it.offset+it.size]...)}
+	result.constants = append(append([]constantField(nil), s.constants...), constant)
+	return result, nil
+}
+
+// OmitConstants returns a copy of the struct whose Unpack, UnpackFrom, IterUnpack and UnpackArray
+// omit the constant values from their results, if omit is set
+func (s *PyStruct) OmitConstants(omit bool) PyStruct {
+	result := *s
+	result.omitConst = omit
+	return result
+}
+
+func (s *PyStruct) constant(item int) (constantField, bool) {
+	for _, c := range s.constants {
+		if c.item == item {
+			return c, true
+		}
+	}
+	return constantField{}, false
+}
+
+func (s *PyStruct) isConstant(item int) bool {
+	_, ok := s.constant(item)
+	return ok
+}
+
+// verifyConstants checks the constants of the packed struct
+func (s *PyStruct) verifyConstants(buffer []byte) error {
+	for _, c := range s.constants {
+		it := s.item(c.item)
+		if !bytes.Equal(buffer[it.offset:it.offset+it.size], c.data) {
+			return &ConstantError{c.item, c.value, s.decodeItem(buffer, it)}
+		}
+	}
+	return nil
+}
+
+// checkNotSpecial returns an error if the item is already a checksum, a constant, an enum, scaled or an integer,
+// the modifiers are exclusive
+func (s *PyStruct) checkNotSpecial(item int) error {
+	_, isEnum := s.enum(item)
+	_, isScaled := s.scaled(item)
+	_, isBigInt := s.bigInt(item)
+	if s.isChecksum(item) || s.isConstant(item) || isEnum || isScaled || isBigInt {
+		return fmt.Errorf("struct.error: item %d is already a checksum, a constant, an enum, scaled or an integer", item)
+	}
+	return nil
+}
+
+// verify checks the constants, the checksums and the enums of the packed struct
+func (s *PyStruct) verify(buffer []byte) error {
+	if err := s.verifyConstants(buffer); err != nil {
+		return err
+	}
+	if err := s.verifyChecksums(buffer); err != nil {
+		return err
+	}
+	return s.verifyEnums(buffer)
+}
+
+// omitted returns the number of values omitted by Unpack
+func (s *PyStruct) omitted() int {
+	if s.omitConst {
+		return len(s.constants)
+	}
+	return 0
+}
+
+// unpackValues unpacks the buffer, omitting the constant values if required
+func (s *PyStruct) unpackValues(buffer []byte) []interface{} {
+	values := s.unpack(buffer)
+	if !s.omitConst || len(s.constants) == 0 {
+		return values
+	}
+	kept := values[:0]
+	for i, value := range values {
+		if !s.isConstant(i) {
+			kept = append(kept, value)
+		}
+	}
+	return kept
+}
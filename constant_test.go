@@ -0,0 +1,162 @@
+package pystruct
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestWithConstant(t *testing.T) {
+	header, _ := NewStruct("<4sIH")
+	s, err := header.WithConstant(0, "RIFF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, err = s.WithConstant(2, uint16(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	buffer, err := s.Pack(uint32(36))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []byte("RIFF\x24\x00\x00\x00\x02\x00"); !reflect.DeepEqual(buffer, expected) {
+		t.Errorf("Expected: %v\nActual: %v\n", expected, buffer)
+	}
+	if repacked, err := s.Pack("RIFX", uint32(36), uint16(9)); err != nil || !reflect.DeepEqual(repacked, buffer) {
+		t.Errorf("Expected the given constants to be ignored, got %v, %v", repacked, err)
+	}
+
+	values, err := s.Unpack(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, []interface{}{"RIFF", uint32(36), uint16(2)}) {
+		t.Errorf("Unexpected values %v", values)
+	}
+
+	omitted := s.OmitConstants(true)
+	values, err = omitted.Unpack(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, []interface{}{uint32(36)}) {
+		t.Errorf("Unexpected values %v", values)
+	}
+	if size, err := UnpackAs[uint32](omitted, buffer); err != nil || size != 36 {
+		t.Errorf("Unexpected value %v, %v", size, err)
+	}
+	if repacked, err := omitted.Pack(values...); err != nil || !reflect.DeepEqual(repacked, buffer) {
+		t.Errorf("Expected the omitted values to round trip, got %v, %v", repacked, err)
+	}
+
+	var mismatch *ConstantError
+	corrupt := append([]byte("RIFX"), buffer[4:]...)
+	if _, err := s.Unpack(corrupt); !errors.As(err, &mismatch) || mismatch.Item != 0 || mismatch.Actual != "RIFX" {
+		t.Errorf("Expected constant error, got %v", err)
+	}
+	corrupt = append(buffer[:8:8], 3, 0)
+	if _, err := s.UnpackFrom(corrupt, 0); !errors.As(err, &mismatch) || mismatch.Expected != uint16(2) {
+		t.Errorf("Expected constant error, got %v", err)
+	}
+
+	if _, err := header.WithConstant(1, "x"); err == nil {
+		t.Error("Expected error for a constant of a wrong type")
+	}
+	if _, err := header.WithConstant(3, uint16(0)); err == nil {
+		t.Error("Expected error for index out of range")
+	}
+	if _, err := s.WithConstant(0, "WAVE"); err == nil {
+		t.Error("Expected error for an item already constant")
+	}
+	if _, err := s.WithChecksum(2, Sum8, 4, 8); err == nil {
+		t.Error("Expected error for a checksum of a constant item")
+	}
+}
+
+func TestViewConstants(t *testing.T) {
+	header, _ := NewStructWithNames("<4sI", "magic", "size")
+	s, err := header.WithConstant(0, "RIFF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer, _ := s.Pack(uint32(36))
+	v, err := NewView(s, buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mismatch *ConstantError
+	if err := v.Set(0, "XXXX"); !errors.As(err, &mismatch) || mismatch.Item != 0 || mismatch.Actual != "XXXX" {
+		t.Errorf("Expected constant error, got %v", err)
+	}
+	if err := v.SetByName("magic", "XXXX"); !errors.As(err, &mismatch) {
+		t.Errorf("Expected constant error, got %v", err)
+	}
+	if err := v.Set(0, "RIFF"); err != nil {
+		t.Error(err)
+	}
+	if err := v.SetByName("size", uint32(40)); err != nil {
+		t.Fatal(err)
+	}
+	values, err := s.Unpack(v.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, []interface{}{"RIFF", uint32(40)}) {
+		t.Errorf("Unexpected values %v", values)
+	}
+}
+
+func TestComposeConstants(t *testing.T) {
+	magic, _ := NewStruct(">2s")
+	magic, _ = magic.WithConstant(0, "\xaa\x55")
+	body, _ := NewStruct(">BH")
+	body, _ = body.WithConstant(0, uint8(1))
+
+	s, err := Concat(magic, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer, err := s.Pack(uint16(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []byte{0xaa, 0x55, 1, 0, 7}; !reflect.DeepEqual(buffer, expected) {
+		t.Errorf("Expected: %v\nActual: %v\n", expected, buffer)
+	}
+
+	outer, _ := NewStruct(">IH")
+	outer, _ = outer.WithConstant(1, uint16(3))
+	embedded, err := Embed(outer, 1, magic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer, err = embedded.Pack(uint32(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []byte{0, 0, 0, 5, 0xaa, 0x55, 0, 3}; !reflect.DeepEqual(buffer, expected) {
+		t.Errorf("Expected: %v\nActual: %v\n", expected, buffer)
+	}
+
+	// the OmitConstants setting of one struct must not apply to the constants of the others
+	if _, err := Concat(magic.OmitConstants(true), body); err == nil {
+		t.Error("Expected an error for structs disagreeing on OmitConstants")
+	}
+	if _, err := Embed(outer, 1, magic.OmitConstants(true)); err == nil {
+		t.Error("Expected an error for structs disagreeing on OmitConstants")
+	}
+	plain, _ := NewStruct(">H")
+	omitted, err := Concat(magic.OmitConstants(true), body.OmitConstants(true), plain.OmitConstants(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := omitted.Unpack([]byte{0xaa, 0x55, 1, 0, 7, 0, 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, []interface{}{uint16(7), uint16(9)}) {
+		t.Errorf("Unexpected values %v", values)
+	}
+}
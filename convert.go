@@ -0,0 +1,532 @@
+package pystruct
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// BytesEncoding selects how 's' and 'c' values are represented in JSON and CSV records
+type BytesEncoding int
+
+const (
+	BytesText   BytesEncoding = iota // raw text, invalid UTF-8 is not preserved by JSON
+	BytesBase64                      // standard base64 encoding
+	BytesHex                         // lower case hex encoding
+)
+
+// RecordOptions configure the JSON and CSV conversion of records.
+//
+// Records of a struct with field names are converted to JSON objects,
+// values of repeated fields are JSON arrays. Records without names are JSON arrays of values.
+// CSV records have a column per value, with a header row if the fields are named.
+//
+// JSON has no NaN and infinities, so they are written as "NaN", "Infinity" and "-Infinity" strings,
+// CSV uses "NaN", "+Inf" and "-Inf". Both forms are accepted when converting back.
+type RecordOptions struct {
+	Names []string      // field names, one per format group, the struct names are used if nil
+	Bytes BytesEncoding // representation of 's' and 'c' values
+}
+
+func (s *PyStruct) recordNames(opts *RecordOptions) ([]string, error) {
+	if opts == nil || opts.Names == nil {
+		return s.names, nil
+	}
+	if len(opts.Names) != len(s.groups) {
+		return nil, fmt.Errorf("struct.error: format has %d fields, got %d names", len(s.groups), len(opts.Names))
+	}
+	return opts.Names, nil
+}
+
+func bytesEncoding(opts *RecordOptions) BytesEncoding {
+	if opts == nil {
+		return BytesText
+	}
+	return opts.Bytes
+}
+
+// records splits buffer into records of s.size bytes
+func (s *PyStruct) records(buffer []byte) ([][]byte, error) {
+	if s.size == 0 {
+		if len(buffer) == 0 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("struct.error: cannot split records of a struct of length 0")
+	}
+	if len(buffer)%s.size != 0 {
+		return nil, fmt.Errorf("struct.error: records require a buffer of a multiple of %d bytes", s.size)
+	}
+	records := make([][]byte, 0, len(buffer)/s.size)
+	for offset := 0; offset < len(buffer); offset += s.size {
+		records = append(records, buffer[offset:offset+s.size])
+	}
+	return records, nil
+}
+
+// formatText formats an unpacked value as text
+func formatText(value interface{}, format cFormatRune, enc BytesEncoding) string {
+	switch format {
+	case tString, tChar:
+		var data []byte
+		if v, ok := value.(rune); ok && format == tChar {
+			data = []byte{byte(v)}
+		} else {
+			data = []byte(fmt.Sprint(value))
+		}
+		switch enc {
+		case BytesBase64:
+			return base64.StdEncoding.EncodeToString(data)
+		case BytesHex:
+			return hex.EncodeToString(data)
+		}
+		return string(data)
+	case tFloat32:
+		return strconv.FormatFloat(float64(value.(float32)), 'g', -1, 32)
+	case tDouble:
+		return strconv.FormatFloat(value.(float64), 'g', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// parseText parses the text representation of a value of the format,
+// the result has the Go type accepted by Pack for the format
+func parseText(text string, format cFormatRune, count int, enc BytesEncoding) (interface{}, error) {
+	switch format {
+	case tString, tChar:
+		var data []byte
+		var err error
+		switch enc {
+		case BytesBase64:
+			data, err = base64.StdEncoding.DecodeString(text)
+		case BytesHex:
+			data, err = hex.DecodeString(text)
+		default:
+			data = []byte(text)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if format == tChar {
+			if len(data) != 1 {
+				return nil, fmt.Errorf("struct.error: char format requires a bytes object of length 1, got %d", len(data))
+			}
+			return rune(data[0]), nil
+		}
+		if len(data) > count {
+			return nil, fmt.Errorf("struct.error: %d bytes don't fit into '%ds'", len(data), count)
+		}
+		return string(data), nil
+	case tBool:
+		return strconv.ParseBool(text)
+	case tFloat32:
+		f, err := strconv.ParseFloat(text, 32)
+		return float32(f), err
+	case tDouble:
+		return strconv.ParseFloat(text, 64)
+	case tUChar, tUShort, tUInt, tULong, tULongLong:
+		n, err := strconv.ParseUint(text, 10, formatAlignmentMap[format]*8)
+		if err != nil {
+			return nil, err
+		}
+		switch format {
+		case tUChar:
+			return uint8(n), nil
+		case tUShort:
+			return uint16(n), nil
+		case tULongLong:
+			return n, nil
+		}
+		return uint32(n), nil
+	case tSChar, tShort, tInt, tLong, tLongLong:
+		n, err := strconv.ParseInt(text, 10, formatAlignmentMap[format]*8)
+		if err != nil {
+			return nil, err
+		}
+		switch format {
+		case tSChar:
+			return int8(n), nil
+		case tShort:
+			return int16(n), nil
+		case tLongLong:
+			return n, nil
+		}
+		return int32(n), nil
+	}
+	return nil, fmt.Errorf("struct.error: bad char ('%c') in struct format", format)
+}
+
+// appendJSONValue appends an unpacked value as JSON
+func appendJSONValue(dst *bytes.Buffer, value interface{}, format cFormatRune, enc BytesEncoding) {
+	switch format {
+	case tString, tChar:
+		data, _ := json.Marshal(formatText(value, format, enc))
+		dst.Write(data)
+		return
+	case tFloat32, tDouble:
+		var f float64
+		if format == tFloat32 {
+			f = float64(value.(float32))
+		} else {
+			f = value.(float64)
+		}
+		switch {
+		case math.IsNaN(f):
+			dst.WriteString(`"NaN"`)
+		case math.IsInf(f, 1):
+			dst.WriteString(`"Infinity"`)
+		case math.IsInf(f, -1):
+			dst.WriteString(`"-Infinity"`)
+		default:
+			dst.WriteString(formatText(value, format, enc))
+		}
+		return
+	}
+	dst.WriteString(fmt.Sprint(value))
+}
+
+// parseJSONValue converts a decoded JSON value to the Go type accepted by Pack for the format
+func parseJSONValue(value interface{}, format cFormatRune, count int, enc BytesEncoding) (interface{}, error) {
+	switch v := value.(type) {
+	case json.Number:
+		if format == tString || format == tChar || format == tBool {
+			return nil, fmt.Errorf("struct.error: number %s is not suitable for '%c'", v, format)
+		}
+		return parseText(v.String(), format, count, enc)
+	case string:
+		switch format {
+		case tString, tChar:
+			return parseText(v, format, count, enc)
+		case tFloat32, tDouble:
+			if v == "NaN" || v == "Infinity" || v == "-Infinity" {
+				return parseText(v, format, count, enc)
+			}
+		}
+	case bool:
+		if format == tBool {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("struct.error: %v is not suitable for '%c'", value, format)
+}
+
+// appendJSONItem appends the value of the i-th item as JSON, the name of an enum value as a string
+// and an N-byte integer as a number
+func (s *PyStruct) appendJSONItem(dst *bytes.Buffer, i int, value interface{}, format cFormatRune, enc BytesEncoding) {
+	if _, ok := s.bigInt(i); ok {
+		dst.WriteString(fmt.Sprint(value))
+		return
+	}
+	if e, ok := s.enum(i); ok {
+		if name, ok := e.name(value); ok {
+			data, _ := json.Marshal(name)
+			dst.Write(data)
+			return
+		}
+		value = e.rawValue(value) // unnamed values as numbers, not as their String
+	}
+	if _, ok := s.scaled(i); ok {
+		format = tDouble
+	}
+	appendJSONValue(dst, value, format, enc)
+}
+
+// parseJSONItem converts a decoded JSON value of the i-th item, accepting the names of enum values
+// and the numbers of scaled and N-byte integer items
+func (s *PyStruct) parseJSONItem(i int, value interface{}, enc BytesEncoding) (interface{}, error) {
+	it := s.item(i)
+	if f, ok := s.bigInt(i); ok {
+		if n, ok := value.(json.Number); ok {
+			return f.parse(n.String())
+		}
+		return nil, fmt.Errorf("struct.error: %v is not suitable for integer item %d", value, i)
+	}
+	if _, ok := s.scaled(i); ok {
+		return parseJSONValue(value, tDouble, it.size, enc)
+	}
+	if e, ok := s.enum(i); ok {
+		if name, ok := value.(string); ok {
+			if v, ok := e.value(name); ok {
+				return v, nil
+			}
+			return nil, fmt.Errorf("struct.error: %q is not a name of enum item %d", name, i)
+		}
+	}
+	return parseJSONValue(value, it.format, it.size, enc)
+}
+
+// formatTextItem formats the value of the i-th item as text, the name of an enum value, an N-byte integer in decimal
+func (s *PyStruct) formatTextItem(i int, value interface{}, enc BytesEncoding) string {
+	if e, ok := s.enum(i); ok {
+		if name, ok := e.name(value); ok {
+			return name
+		}
+		value = e.rawValue(value)
+	}
+	if _, ok := s.scaled(i); ok {
+		return formatText(value, tDouble, enc)
+	}
+	if _, ok := s.bigInt(i); ok {
+		return fmt.Sprint(value)
+	}
+	return formatText(value, s.item(i).format, enc)
+}
+
+// parseTextItem parses the text of the i-th item, accepting the names of enum values
+// and the numbers of scaled and N-byte integer items
+func (s *PyStruct) parseTextItem(i int, text string, enc BytesEncoding) (interface{}, error) {
+	if e, ok := s.enum(i); ok {
+		if v, ok := e.value(text); ok {
+			return v, nil
+		}
+	}
+	it := s.item(i)
+	if _, ok := s.scaled(i); ok {
+		return parseText(text, tDouble, it.size, enc)
+	}
+	if f, ok := s.bigInt(i); ok {
+		return f.parse(text)
+	}
+	return parseText(text, it.format, it.size, enc)
+}
+
+func (s *PyStruct) appendJSONRecord(dst *bytes.Buffer, values []interface{}, names []string, enc BytesEncoding) {
+	if names == nil {
+		dst.WriteByte('[')
+		for i, it := range s.items() {
+			if i > 0 {
+				dst.WriteByte(',')
+			}
+			s.appendJSONItem(dst, i, values[i], it.format, enc)
+		}
+		dst.WriteByte(']')
+		return
+	}
+
+	index := 0
+	dst.WriteByte('{')
+	for i, group := range s.groups {
+		if i > 0 {
+			dst.WriteByte(',')
+		}
+		key, _ := json.Marshal(names[i])
+		dst.Write(key)
+		dst.WriteByte(':')
+
+		if group.format == tString || group.number == 1 {
+			s.appendJSONItem(dst, index, values[index], group.format, enc)
+			index++
+			continue
+		}
+		dst.WriteByte('[')
+		for num := 0; num < group.number; num++ {
+			if num > 0 {
+				dst.WriteByte(',')
+			}
+			s.appendJSONItem(dst, index, values[index], group.format, enc)
+			index++
+		}
+		dst.WriteByte(']')
+	}
+	dst.WriteByte('}')
+}
+
+// ToJSON converts the records packed in buffer to a JSON array,
+// the buffer’s size in bytes must be a multiple of the size required by the format
+func (s *PyStruct) ToJSON(buffer []byte, opts *RecordOptions) ([]byte, error) {
+	names, err := s.recordNames(opts)
+	if err != nil {
+		return nil, err
+	}
+	records, err := s.records(buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	out.WriteByte('[')
+	for i, record := range records {
+		if err := s.verify(record); err != nil {
+			return nil, fmt.Errorf("record %d: %w", i, err)
+		}
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		s.appendJSONRecord(&out, s.unpack(record), names, bytesEncoding(opts))
+	}
+	out.WriteByte(']')
+	return out.Bytes(), nil
+}
+
+// jsonRecordValues returns the values of a decoded JSON record in the packing order
+func (s *PyStruct) jsonRecordValues(record interface{}, names []string) ([]interface{}, error) {
+	if names == nil {
+		values, ok := record.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("struct.error: record must be a JSON array")
+		}
+		return values, nil
+	}
+
+	object, ok := record.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("struct.error: record must be a JSON object")
+	}
+	if len(object) != len(names) {
+		return nil, fmt.Errorf("struct.error: record requires %d fields, got %d", len(names), len(object))
+	}
+	values := make([]interface{}, 0, s.items_num)
+	for i, group := range s.groups {
+		value, ok := object[names[i]]
+		if !ok {
+			return nil, fmt.Errorf("struct.error: record has no field %q", names[i])
+		}
+		if group.format == tString || group.number == 1 {
+			values = append(values, value)
+			continue
+		}
+		array, ok := value.([]interface{})
+		if !ok || len(array) != group.number {
+			return nil, fmt.Errorf("struct.error: field %q requires an array of %d values", names[i], group.number)
+		}
+		values = append(values, array...)
+	}
+	return values, nil
+}
+
+// FromJSON packs the records of a JSON array, as produced by ToJSON,
+// the values are validated against the format
+func (s *PyStruct) FromJSON(data []byte, opts *RecordOptions) ([]byte, error) {
+	names, err := s.recordNames(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&records); err != nil {
+		return nil, err
+	}
+
+	items := s.items()
+	buffer := make([]byte, 0, len(records)*s.size)
+	for r, record := range records {
+		values, err := s.jsonRecordValues(record, names)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", r, err)
+		}
+		if len(values) != len(items) {
+			return nil, fmt.Errorf("record %d: struct.error: format requires %d items, got %d", r, len(items), len(values))
+		}
+		for i := range items {
+			if values[i], err = s.parseJSONItem(i, values[i], bytesEncoding(opts)); err != nil {
+				return nil, fmt.Errorf("record %d item %d: %w", r, i, err)
+			}
+		}
+		if buffer, err = s.AppendPack(buffer, values...); err != nil {
+			return nil, fmt.Errorf("record %d: %w", r, err)
+		}
+	}
+	return buffer, nil
+}
+
+// csvHeader returns the CSV column names, repeated values are suffixed by their index like "xy[1]"
+func (s *PyStruct) csvHeader(names []string) []string {
+	header := make([]string, 0, s.items_num)
+	for _, it := range s.items() {
+		name := names[it.group]
+		if group := s.groups[it.group]; group.format != tString && group.number != 1 {
+			name = fmt.Sprintf("%s[%d]", name, it.index)
+		}
+		header = append(header, name)
+	}
+	return header
+}
+
+// ToCSV writes the records packed in buffer as CSV rows, with a header row if the fields are named,
+// the buffer’s size in bytes must be a multiple of the size required by the format
+func (s *PyStruct) ToCSV(w io.Writer, buffer []byte, opts *RecordOptions) error {
+	names, err := s.recordNames(opts)
+	if err != nil {
+		return err
+	}
+	records, err := s.records(buffer)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if names != nil {
+		if err := writer.Write(s.csvHeader(names)); err != nil {
+			return err
+		}
+	}
+
+	items := s.items()
+	row := make([]string, len(items))
+	for r, record := range records {
+		if err := s.verify(record); err != nil {
+			return fmt.Errorf("record %d: %w", r, err)
+		}
+		for i, value := range s.unpack(record) {
+			row[i] = s.formatTextItem(i, value, bytesEncoding(opts))
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// FromCSV packs the CSV rows, as produced by ToCSV,
+// the header row is required and verified if the fields are named
+func (s *PyStruct) FromCSV(r io.Reader, opts *RecordOptions) ([]byte, error) {
+	names, err := s.recordNames(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(r)
+	items := s.items()
+	reader.FieldsPerRecord = len(items)
+
+	if names != nil {
+		header, err := reader.Read()
+		if err != nil {
+			return nil, err
+		}
+		for i, name := range s.csvHeader(names) {
+			if header[i] != name {
+				return nil, fmt.Errorf("struct.error: CSV column %d is %q, expected %q", i, header[i], name)
+			}
+		}
+	}
+
+	var buffer []byte
+	values := make([]interface{}, len(items))
+	for line := 1; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i := range items {
+			if values[i], err = s.parseTextItem(i, row[i], bytesEncoding(opts)); err != nil {
+				return nil, fmt.Errorf("row %d column %d: %w", line, i, err)
+			}
+		}
+		if buffer, err = s.AppendPack(buffer, values...); err != nil {
+			return nil, fmt.Errorf("row %d: %w", line, err)
+		}
+	}
+	return buffer, nil
+}
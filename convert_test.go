@@ -0,0 +1,173 @@
+package pystruct
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func packRecords(t *testing.T, s PyStruct, records ...[]interface{}) []byte {
+	var buffer []byte
+	for _, record := range records {
+		var err error
+		if buffer, err = s.AppendPack(buffer, record...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buffer
+}
+
+func TestToJSON(t *testing.T) {
+	s, err := NewStructWithNames("<B3s2hd", "kind", "label", "xy", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer := packRecords(t, s,
+		[]interface{}{uint8(1), "ab", int16(2), int16(-3), 1.5},
+		[]interface{}{uint8(2), "xyz", int16(0), int16(0), math.Inf(-1)},
+	)
+
+	data, err := s.ToJSON(buffer, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `[{"kind":1,"label":"ab\u0000","xy":[2,-3],"value":1.5},{"kind":2,"label":"xyz","xy":[0,0],"value":"-Infinity"}]`
+	if string(data) != expected {
+		t.Errorf("Expected: %s\nActual: %s", expected, data)
+	}
+
+	packed, err := s.FromJSON(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed, buffer) {
+		t.Errorf("Expected: %v\nActual: %v", buffer, packed)
+	}
+}
+
+func TestToJSONUnnamed(t *testing.T) {
+	s, err := NewStruct("<c2sf?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer := packRecords(t, s, []interface{}{'a', "\xff\x00", float32(math.NaN()), true})
+
+	for _, c := range []struct {
+		enc      BytesEncoding
+		expected string
+	}{
+		{BytesHex, `[["61","ff00","NaN",true]]`},
+		{BytesBase64, `[["YQ==","/wA=","NaN",true]]`},
+	} {
+		data, err := s.ToJSON(buffer, &RecordOptions{Bytes: c.enc})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != c.expected {
+			t.Errorf("Expected: %s\nActual: %s", c.expected, data)
+		}
+
+		packed, err := s.FromJSON(data, &RecordOptions{Bytes: c.enc})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(packed, buffer) {
+			t.Errorf("Expected: %v\nActual: %v", buffer, packed)
+		}
+	}
+}
+
+func TestFromJSONValidation(t *testing.T) {
+	s, err := NewStruct("<bH3s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &RecordOptions{Names: []string{"a", "b", "c"}}
+
+	for _, data := range []string{
+		`[{"a":128,"b":1,"c":"x"}]`,
+		`[{"a":1,"b":-1,"c":"x"}]`,
+		`[{"a":1.5,"b":1,"c":"x"}]`,
+		`[{"a":1,"b":1,"c":"long"}]`,
+		`[{"a":1,"b":1}]`,
+		`[{"a":"1","b":1,"c":"x"}]`,
+		`[[1,1,"x"]]`,
+		`{"a":1,"b":1,"c":"x"}`,
+	} {
+		if _, err := s.FromJSON([]byte(data), opts); err == nil {
+			t.Errorf("expected error for %s", data)
+		}
+	}
+
+	if _, err := s.FromJSON([]byte(`[{"a":-128,"b":65535,"c":"x"}]`), opts); err != nil {
+		t.Error("Unbound error:", err)
+	}
+}
+
+func TestToCSV(t *testing.T) {
+	s, err := NewStructWithNames("<B3s2hd", "kind", "label", "xy", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer := packRecords(t, s,
+		[]interface{}{uint8(1), "a,b", int16(2), int16(-3), 1.5},
+		[]interface{}{uint8(2), "xyz", int16(0), int16(0), math.NaN()},
+	)
+
+	var out bytes.Buffer
+	if err := s.ToCSV(&out, buffer, nil); err != nil {
+		t.Fatal(err)
+	}
+	expected := "kind,label,xy[0],xy[1],value\n1,\"a,b\",2,-3,1.5\n2,xyz,0,0,NaN\n"
+	if out.String() != expected {
+		t.Errorf("Expected: %q\nActual: %q", expected, out.String())
+	}
+
+	packed, err := s.FromCSV(strings.NewReader(out.String()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := s.Unpack(packed[s.Size():])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsNaN(values[4].(float64)) || !bytes.Equal(packed[:s.Size()], buffer[:s.Size()]) {
+		t.Errorf("Expected: %v\nActual: %v", buffer, packed)
+	}
+
+	if _, err := s.FromCSV(strings.NewReader("kind,name,xy[0],xy[1],value\n"), nil); err == nil {
+		t.Error("expected error for wrong header")
+	}
+	if _, err := s.FromCSV(strings.NewReader(expected+"3,abc,70000,0,1\n"), nil); err == nil {
+		t.Error("expected error for out of range value")
+	}
+}
+
+func TestToCSVUnnamed(t *testing.T) {
+	s, err := NewStruct("<?f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer := packRecords(t, s, []interface{}{true, float32(math.Inf(1))})
+
+	var out bytes.Buffer
+	if err := s.ToCSV(&out, buffer, nil); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "true,+Inf\n" {
+		t.Errorf("unexpected CSV: %q", out.String())
+	}
+
+	packed, err := s.FromCSV(strings.NewReader(out.String()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed, buffer) {
+		t.Errorf("Expected: %v\nActual: %v", buffer, packed)
+	}
+
+	if err := s.ToCSV(&out, buffer[1:], nil); err == nil {
+		t.Error("expected error for partial record")
+	}
+}
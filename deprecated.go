@@ -51,6 +51,10 @@ func CalcSize_old(format string) (int, error) {
 	num := 0
 	size := 0
 
+	if len(format) == 0 {
+		return -1, fmt.Errorf("struct.error: empty struct format")
+	}
+
 	if _, ok := cOrderMap[rune(format[0])]; ok {
 		format = format[1:]
 	}
@@ -249,7 +253,8 @@ func Unpack_old(format string, buffer []byte) ([]interface{}, error) {
 func IterUnpack_old(format string, buffer []byte) (<-chan interface{}, <-chan error) {
 
 	parsedValues := make(chan interface{})
-	errors := make(chan error)
+	// buffered, so an error doesn't block while the caller is still reading values
+	errors := make(chan error, 1)
 
 	go func() {
 		defer close(parsedValues)
@@ -324,7 +329,7 @@ func IterUnpack_old(format string, buffer []byte) (<-chan interface{}, <-chan er
 // The buffer’s size in bytes, starting at position offset,
 // must be at least the size required by the format, as reflected by CalcSize().
 func UnpackFrom_old(format string, buffer []byte, offset int) ([]interface{}, error) {
-	if offset >= len(buffer) {
+	if offset < 0 || offset >= len(buffer) {
 		return nil, fmt.Errorf("offset is out of range")
 	}
 	return Unpack_old(format, buffer[offset:])
@@ -0,0 +1,123 @@
+package pystruct
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// dumpBytesPerLine is the number of raw bytes shown in a single dump line
+const dumpBytesPerLine = 16
+
+// Dump writes an annotated hex dump of buffer decoded according to the format string format.
+// Each value is shown with its offset, raw bytes, format and decoded value,
+// pad bytes of native alignment and the bytes after the struct are marked as "pad" and "trailing".
+// If buffer is shorter than the struct size, the values that don't fit are marked as "missing".
+func (s *PyStruct) Dump(w io.Writer, buffer []byte) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "OFFSET\tBYTES\tFORMAT\tFIELD\tVALUE")
+
+	offset := 0
+	for _, it := range s.items() {
+		if it.index == 0 && it.offset > offset {
+			dumpRow(tw, buffer, offset, it.offset, "pad", "", "")
+		}
+		offset = it.offset + it.size
+
+		format := string(it.format)
+		if it.format == tString {
+			format = fmt.Sprintf("%d%c", it.size, it.format)
+		}
+		field := s.fieldName(it.group)
+		if field == "" {
+			field = fmt.Sprintf("#%d", it.group)
+		}
+		if it.format != tString && s.groups[it.group].number > 1 {
+			field = fmt.Sprintf("%s[%d]", field, it.index)
+		}
+
+		value := "missing"
+		if offset <= len(buffer) {
+			value = s.dumpValue(buffer, it)
+		}
+		dumpRow(tw, buffer, it.offset, offset, format, field, value)
+	}
+
+	if s.size > offset {
+		dumpRow(tw, buffer, offset, s.size, "pad", "", "")
+	}
+	if len(buffer) > s.size {
+		dumpRow(tw, buffer, s.size, len(buffer), "", "", "trailing")
+	}
+	return tw.Flush()
+}
+
+// Dump writes an annotated hex dump of buffer decoded according to the format string format.
+func Dump(format string, w io.Writer, buffer []byte) error {
+	s, err := compile(format)
+	if err != nil {
+		return err
+	}
+	return s.Dump(w, buffer)
+}
+
+// dumpValue formats the value of the item,
+// the raw value of an enum is followed by its name and the raw value of a scaled item by the scaled one,
+// an N-byte integer is written in decimal
+func (s *PyStruct) dumpValue(buffer []byte, it item) string {
+	if sc, ok := s.scaled(it.seq); ok {
+		raw := parseValue(buffer[it.offset:it.offset+it.size], it.format, s.groups[it.group].order)
+		return fmt.Sprintf("%v (%s)", raw, formatText(sc.convert(raw), tDouble, BytesText))
+	}
+	if _, ok := s.bigInt(it.seq); ok {
+		return fmt.Sprint(s.decodeItem(buffer, it))
+	}
+	e, ok := s.enum(it.seq)
+	if !ok {
+		return formatDumpValue(s.decodeItem(buffer, it), it.format)
+	}
+	raw := parseValue(buffer[it.offset:it.offset+it.size], it.format, s.groups[it.group].order)
+	name, ok := e.name(raw)
+	if !ok {
+		name = "unknown"
+	}
+	return fmt.Sprintf("%v (%s)", raw, name)
+}
+
+// dumpRow writes the bytes of buffer[start:end] wrapped by dumpBytesPerLine,
+// the annotations are written on the first line only
+func dumpRow(w io.Writer, buffer []byte, start, end int, format, field, value string) {
+	for line := start; line < end || line == start; line += dumpBytesPerLine {
+		lineEnd := line + dumpBytesPerLine
+		if lineEnd > end {
+			lineEnd = end
+		}
+		fmt.Fprintf(w, "%04x\t%s\t%s\t%s\t%s\n", line, hexBytes(buffer, line, lineEnd), format, field, value)
+		format, field, value = "", "", ""
+	}
+}
+
+// hexBytes formats buffer[start:end] as space separated hex bytes, bytes out of buffer are shown as "--"
+func hexBytes(buffer []byte, start, end int) string {
+	var sb strings.Builder
+	for i := start; i < end; i++ {
+		if i > start {
+			sb.WriteByte(' ')
+		}
+		if i < len(buffer) {
+			fmt.Fprintf(&sb, "%02x", buffer[i])
+		} else {
+			sb.WriteString("--")
+		}
+	}
+	return sb.String()
+}
+
+func formatDumpValue(value interface{}, format cFormatRune) string {
+	switch format {
+	case tString, tChar:
+		return fmt.Sprintf("%q", value)
+	}
+	return fmt.Sprint(value)
+}
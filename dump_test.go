@@ -0,0 +1,74 @@
+package pystruct
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	s, err := NewStructWithNames("@b2hi3s", "kind", "xy", "value", "label")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer := []byte{1, 0xee, 2, 0, 0xfd, 0xff, 0xee, 0xee, 7, 0, 0, 0, 97, 98, 99, 0xaa, 0xbb}
+	if getNativeOrder() != binary.LittleEndian {
+		t.Skip("expected dump is for little-endian native order")
+	}
+
+	var out bytes.Buffer
+	if err := s.Dump(&out, buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `OFFSET  BYTES        FORMAT  FIELD  VALUE
+0000    01           b       kind   1
+0001    ee           pad
+0002    02 00        h       xy[0]  2
+0004    fd ff        h       xy[1]  -3
+0006    ee ee        pad
+0008    07 00 00 00  i       value  7
+000c    61 62 63     3s      label  "abc"
+000f    aa bb                       trailing
+`
+	if actual := trimLines(out.String()); actual != expected {
+		t.Errorf("Expected:\n%s\nActual:\n%s", expected, actual)
+	}
+}
+
+func TestDumpShortBuffer(t *testing.T) {
+	var out bytes.Buffer
+	if err := Dump("<hq", &out, []byte{1, 0, 2, 0}); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got:\n%s", out.String())
+	}
+	if !strings.Contains(lines[2], "02 00 -- -- -- -- -- --") || !strings.HasSuffix(lines[2], "missing") {
+		t.Errorf("missing bytes are not marked: %q", lines[2])
+	}
+}
+
+func TestDumpLongString(t *testing.T) {
+	var out bytes.Buffer
+	if err := Dump("<20s", &out, []byte("0123456789abcdefghij")); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[2], "0010    67 68 69 6a") {
+		t.Errorf("long value is not wrapped:\n%s", out.String())
+	}
+}
+
+// trimLines removes the trailing spaces of the tabwriter empty columns
+func trimLines(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,162 @@
+package pystruct
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// integer is the constraint of the Go types of enum values
+type integer interface {
+	~int8 | ~int16 | ~int32 | ~int64 | ~uint8 | ~uint16 | ~uint32 | ~uint64
+}
+
+// EnumError is returned for a value of an enum item without a name
+type EnumError struct {
+	Item  int         // index of the enum value, like in the result of Fields
+	Value interface{} // the unknown value
+}
+
+func (e *EnumError) Error() string {
+	return fmt.Sprintf("struct.error: %v is not a known value of enum item %d", e.Value, e.Item)
+}
+
+// enumField is an integer item unpacked as a named Go type
+type enumField struct {
+	item    int
+	typ     reflect.Type           // named Go type of the values
+	raw     reflect.Type           // Go type of the item values
+	names   map[interface{}]string // names of the values of typ
+	values  map[string]interface{} // values of typ by name
+	unknown interface{}            // value of typ returned for unknown values, nil to report an *EnumError
+}
+
+// WithEnum returns a copy of the struct whose item-th value is unpacked as T,
+// a named integer type of the same kind as the Go type of the item, like `type Kind uint8` for 'B'.
+// Only the values of names are valid: Pack rejects the others and Unpack reports them as *EnumError.
+// Dump, ToJSON and ToCSV write the names of the values, FromJSON and FromCSV accept them.
+func WithEnum[T integer](s PyStruct, item int, names map[T]string) (PyStruct, error) {
+	return withEnum(s, item, names, nil)
+}
+
+// WithEnumDefault works like WithEnum, but Unpack returns unknown for the values without a name
+func WithEnumDefault[T integer](s PyStruct, item int, names map[T]string, unknown T) (PyStruct, error) {
+	return withEnum(s, item, names, unknown)
+}
+
+// WithEnumByName works like WithEnum for the named field, which must hold a single value
+func WithEnumByName[T integer](s PyStruct, field string, names map[T]string) (PyStruct, error) {
+	item, err := s.namedItem(field)
+	if err != nil {
+		return PyStruct{}, err
+	}
+	return withEnum(s, item, names, nil)
+}
+
+// WithEnumDefaultByName works like WithEnumDefault for the named field, which must hold a single value
+func WithEnumDefaultByName[T integer](s PyStruct, field string, names map[T]string, unknown T) (PyStruct, error) {
+	item, err := s.namedItem(field)
+	if err != nil {
+		return PyStruct{}, err
+	}
+	return withEnum(s, item, names, unknown)
+}
+
+func withEnum[T integer](s PyStruct, item int, names map[T]string, unknown interface{}) (PyStruct, error) {
+	if item < 0 || item >= s.items_num {
+		return PyStruct{}, fmt.Errorf("struct.error: item index %d out of range [0, %d)", item, s.items_num)
+	}
+	if err := s.checkNotSpecial(item); err != nil {
+		return PyStruct{}, err
+	}
+	it := s.item(item)
+	typ := reflect.TypeOf(T(0))
+	if it.format == tChar || it.format == tString || it.format == tBool || !acceptsItem(typ, it.format) {
+		return PyStruct{}, fmt.Errorf("struct.error: %s can't hold the values of item %d (%s)", typ, item, cFormatStringMap[it.format])
+	}
+
+	e := enumField{
+		item:    item,
+		typ:     typ,
+		raw:     goTypeMap[it.format],
+		names:   make(map[interface{}]string, len(names)),
+		values:  make(map[string]interface{}, len(names)),
+		unknown: unknown,
+	}
+	for value, name := range names {
+		if other, ok := e.values[name]; ok {
+			return PyStruct{}, fmt.Errorf("struct.error: enum values %v and %v have the same name %q", other, value, name)
+		}
+		e.names[value] = name
+		e.values[name] = value
+	}
+
+	result := s
+	result.enums = append(append([]enumField(nil), s.enums...), e)
+	return result, nil
+}
+
+func (s *PyStruct) enum(item int) (enumField, bool) {
+	for _, e := range s.enums {
+		if e.item == item {
+			return e, true
+		}
+	}
+	return enumField{}, false
+}
+
+// convert returns the decoded value as the enum type, or the unknown value
+func (e enumField) convert(value interface{}) interface{} {
+	converted := reflect.ValueOf(value).Convert(e.typ).Interface()
+	if _, ok := e.names[converted]; !ok && e.unknown != nil {
+		return e.unknown
+	}
+	return converted
+}
+
+// known reports whether the value is a named value of the enum,
+// values of other kinds are left to be rejected by the packing
+func (e enumField) known(value interface{}) bool {
+	if v := reflect.ValueOf(value); !v.IsValid() || v.Kind() != e.typ.Kind() {
+		return true
+	}
+	_, ok := e.name(value)
+	return ok
+}
+
+// rawValue returns the value as the Go type of the item accepted by the packing
+func (e enumField) rawValue(value interface{}) interface{} {
+	if v := reflect.ValueOf(value); v.IsValid() && v.Kind() == e.raw.Kind() {
+		return v.Convert(e.raw).Interface()
+	}
+	return value
+}
+
+// name returns the name of the value
+func (e enumField) name(value interface{}) (string, bool) {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() || v.Kind() != e.typ.Kind() {
+		return "", false
+	}
+	name, ok := e.names[v.Convert(e.typ).Interface()]
+	return name, ok
+}
+
+// value returns the value of the name
+func (e enumField) value(name string) (interface{}, bool) {
+	value, ok := e.values[name]
+	return value, ok
+}
+
+// verifyEnums checks the enums without an unknown value of the packed struct
+func (s *PyStruct) verifyEnums(buffer []byte) error {
+	for _, e := range s.enums {
+		if e.unknown != nil {
+			continue
+		}
+		value := s.decodeItem(buffer, s.item(e.item))
+		if _, ok := e.names[value]; !ok {
+			return &EnumError{e.item, value}
+		}
+	}
+	return nil
+}
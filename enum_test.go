@@ -0,0 +1,204 @@
+package pystruct
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type msgKind uint8
+
+const (
+	kindPing msgKind = iota + 1
+	kindData
+	kindUnknown msgKind = 0xff
+)
+
+var msgKindNames = map[msgKind]string{kindPing: "ping", kindData: "data"}
+
+func (k msgKind) String() string {
+	if name, ok := msgKindNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+func TestWithEnum(t *testing.T) {
+	record, _ := NewStructWithNames("<BH", "kind", "length")
+	s, err := WithEnum(record, 0, msgKindNames)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buffer, err := s.Pack(kindData, uint16(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, _ := s.Pack(uint8(2), uint16(3)); !bytes.Equal(plain, buffer) {
+		t.Errorf("Expected: %v\nActual: %v\n", buffer, plain)
+	}
+	values, err := s.Unpack(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, []interface{}{kindData, uint16(3)}) {
+		t.Errorf("Unexpected values %v", values)
+	}
+
+	var unknown *EnumError
+	if _, err := s.Pack(msgKind(7), uint16(0)); !errors.As(err, &unknown) || unknown.Item != 0 {
+		t.Errorf("Expected enum error, got %v", err)
+	}
+	if _, err := s.Unpack([]byte{7, 0, 0}); !errors.As(err, &unknown) || unknown.Value != msgKind(7) {
+		t.Errorf("Expected enum error, got %v", err)
+	}
+	v, _ := NewView(s, buffer)
+	if err := v.Set(0, msgKind(9)); !errors.As(err, &unknown) {
+		t.Errorf("Expected enum error, got %v", err)
+	}
+
+	lenient, err := WithEnumDefault(record, 0, msgKindNames, kindUnknown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err = lenient.Unpack([]byte{7, 0, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[0] != kindUnknown {
+		t.Errorf("Expected the unknown sentinel, got %v", values[0])
+	}
+
+	type message struct {
+		Kind   msgKind
+		Length uint16
+	}
+	var messages []message
+	if err := s.UnpackSlice(buffer, &messages); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(messages, []message{{kindData, 3}}) {
+		t.Errorf("Unexpected messages %v", messages)
+	}
+}
+
+func TestWithEnumByName(t *testing.T) {
+	record, _ := NewStructWithNames("<B2H", "kind", "lengths")
+	s, err := WithEnumByName(record, "kind", msgKindNames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := s.Unpack([]byte{2, 0, 0, 0, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[0] != kindData {
+		t.Errorf("Expected %v, got %v", kindData, values[0])
+	}
+	if _, err := WithEnumDefaultByName(record, "kind", msgKindNames, kindUnknown); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := WithEnumByName(record, "lengths", map[uint16]string{1: "one"}); err == nil {
+		t.Error("Expected an error for a repeated field")
+	}
+	if _, err := WithEnumByName(record, "type", msgKindNames); err == nil {
+		t.Error("Expected an error for an unknown field")
+	}
+	unnamed, _ := NewStruct("<BH")
+	if _, err := WithEnumByName(unnamed, "kind", msgKindNames); err == nil {
+		t.Error("Expected an error for a struct without names")
+	}
+}
+
+func TestEnumText(t *testing.T) {
+	record, _ := NewStructWithNames("<BH", "kind", "length")
+	s, _ := WithEnum(record, 0, msgKindNames)
+	buffer, _ := s.Pack(kindPing, uint16(1))
+
+	data, err := s.ToJSON(buffer, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `[{"kind":"ping","length":1}]` {
+		t.Errorf("Unexpected JSON %s", data)
+	}
+	packed, err := s.FromJSON([]byte(`[{"kind":"ping","length":1},{"kind":2,"length":0}]`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed[:3], buffer) || packed[3] != 2 {
+		t.Errorf("Unexpected packed records %v", packed)
+	}
+	if _, err := s.FromJSON([]byte(`[{"kind":"pong","length":1}]`), nil); err == nil {
+		t.Error("Expected error for an unknown name")
+	}
+
+	var csv bytes.Buffer
+	if err := s.ToCSV(&csv, buffer, nil); err != nil {
+		t.Fatal(err)
+	}
+	if csv.String() != "kind,length\nping,1\n" {
+		t.Errorf("Unexpected CSV %q", csv.String())
+	}
+	if packed, err := s.FromCSV(&csv, nil); err != nil || !bytes.Equal(packed, buffer) {
+		t.Errorf("Unexpected packed records %v, %v", packed, err)
+	}
+
+	var dump strings.Builder
+	if err := s.Dump(&dump, []byte{1, 0, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(dump.String(), "1 (ping)") {
+		t.Errorf("Expected the enum name in the dump:\n%s", dump.String())
+	}
+	dump.Reset()
+	s.Dump(&dump, []byte{7, 0, 0})
+	if !strings.Contains(dump.String(), "7 (unknown)") {
+		t.Errorf("Expected an unknown enum value in the dump:\n%s", dump.String())
+	}
+
+	// unnamed values, like the unknown value, are written as numbers rather than with their String method
+	lenient, _ := WithEnumDefault(record, 0, msgKindNames, kindUnknown)
+	sentinel := []byte{7, 1, 0}
+	if data, err := lenient.ToJSON(sentinel, nil); err != nil || string(data) != `[{"kind":255,"length":1}]` {
+		t.Errorf("Unexpected JSON %s, %v", data, err)
+	}
+	csv.Reset()
+	if err := lenient.ToCSV(&csv, sentinel, nil); err != nil {
+		t.Fatal(err)
+	}
+	if csv.String() != "kind,length\n255,1\n" {
+		t.Errorf("Unexpected CSV %q", csv.String())
+	}
+	// read back as a number, then rejected by the packing like any unnamed value
+	var unknown *EnumError
+	if _, err := lenient.FromCSV(&csv, nil); !errors.As(err, &unknown) || unknown.Value != uint8(0xff) {
+		t.Errorf("Expected an unknown enum value error, got %v", err)
+	}
+}
+
+func TestWithEnumErrors(t *testing.T) {
+	record, _ := NewStruct("<BHc")
+	if _, err := WithEnum(record, 1, msgKindNames); err == nil {
+		t.Error("Expected error for a type of another kind")
+	}
+	if _, err := WithEnum(record, 3, msgKindNames); err == nil {
+		t.Error("Expected error for index out of range")
+	}
+	if _, err := WithEnum(record, 2, map[int32]string{65: "A"}); err == nil {
+		t.Error("Expected error for a char item")
+	}
+	if _, err := WithEnum(record, 0, map[msgKind]string{1: "a", 2: "a"}); err == nil {
+		t.Error("Expected error for duplicate names")
+	}
+	s, _ := WithEnum(record, 0, msgKindNames)
+	if _, err := WithEnum(s, 0, msgKindNames); err == nil {
+		t.Error("Expected error for an item already an enum")
+	}
+	if _, err := s.WithConstant(0, kindPing); err == nil {
+		t.Error("Expected error for a constant of an enum item")
+	}
+}
@@ -0,0 +1,176 @@
+package pystruct
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+)
+
+// DefaultMaxFrameSize is the frame size limit of a Framer if FramerOptions.MaxFrameSize is 0
+const DefaultMaxFrameSize = 1 << 20
+
+// FramerOptions configures a Framer
+type FramerOptions struct {
+	// Magic are the bytes every header starts with, after a mismatch
+	// the stream is searched for the next occurrence, nil disables the check
+	Magic []byte
+	// MaxFrameSize is the largest accepted size of a frame including the header,
+	// DefaultMaxFrameSize if 0
+	MaxFrameSize int
+	// LengthIncludesHeader tells that the length field counts the header bytes too
+	LengthIncludesHeader bool
+}
+
+// FrameSizeError is returned by Framer for a length field value out of the limits
+type FrameSizeError struct {
+	Size int64 // frame size including the header
+	Min  int   // header size
+	Max  int   // frame size limit
+}
+
+func (e *FrameSizeError) Error() string {
+	return fmt.Sprintf("struct.error: frame size %d out of range [%d, %d]", e.Size, e.Min, e.Max)
+}
+
+// Frame is a header and the payload following it
+type Frame struct {
+	Header  []interface{} // values of the header
+	Payload []byte        // bytes following the header, as counted by the length field
+}
+
+// Framer reads frames of a header followed by a payload, whose size is given by a length field of the header
+type Framer struct {
+	r       *bufio.Reader
+	header  PyStruct
+	length  item // length item of the header
+	opts    FramerOptions
+	skipped int64
+}
+
+// NewFramer returns a framer reading from r,
+// length is the index of the length value in the header, like in the result of Fields
+func NewFramer(r io.Reader, header PyStruct, length int, opts *FramerOptions) (*Framer, error) {
+	if length < 0 || length >= header.items_num {
+		return nil, fmt.Errorf("struct.error: item index %d out of range [0, %d)", length, header.items_num)
+	}
+	it := header.item(length)
+	if _, scaled := header.scaled(length); scaled || goTypeMap[it.format].Kind() < reflect.Int8 || goTypeMap[it.format].Kind() > reflect.Uint64 || it.format == tChar {
+		return nil, fmt.Errorf("struct.error: length item %d is not an integer", length)
+	}
+
+	f := &Framer{header: header, length: it}
+	if opts != nil {
+		f.opts = *opts
+	}
+	if f.opts.MaxFrameSize == 0 {
+		f.opts.MaxFrameSize = DefaultMaxFrameSize
+	}
+	if len(f.opts.Magic) > header.size {
+		return nil, fmt.Errorf("struct.error: magic of %d bytes is longer than the header", len(f.opts.Magic))
+	}
+	if f.opts.MaxFrameSize < header.size {
+		return nil, fmt.Errorf("struct.error: max frame size %d is smaller than the header", f.opts.MaxFrameSize)
+	}
+
+	size := 4096
+	if header.size > size {
+		size = header.size
+	}
+	f.r = bufio.NewReaderSize(r, size)
+	return f, nil
+}
+
+// Skipped returns the number of bytes discarded while resynchronizing on the magic
+func (f *Framer) Skipped() int64 {
+	return f.skipped
+}
+
+// Next reads the next frame, it returns io.EOF at the end of the stream
+// and io.ErrUnexpectedEOF if the stream ends inside a frame.
+// With a magic, a frame of a bad size or header checksum is skipped like a corrupt magic,
+// otherwise a *FrameSizeError or *ChecksumError is returned.
+func (f *Framer) Next() (Frame, error) {
+	for {
+		data, err := f.r.Peek(f.header.size)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				if len(data) == 0 {
+					return Frame{}, io.EOF
+				}
+				return Frame{}, io.ErrUnexpectedEOF
+			}
+			return Frame{}, err
+		}
+
+		if !bytes.HasPrefix(data, f.opts.Magic) {
+			f.resync()
+			continue
+		}
+		if err := f.header.verify(data); err != nil {
+			if f.opts.Magic != nil {
+				f.resync()
+				continue
+			}
+			return Frame{}, err
+		}
+
+		header := f.header.unpackValues(data)
+		size, ok := f.frameSize(f.header.decodeItem(data, f.length))
+		if !ok {
+			if f.opts.Magic != nil {
+				f.resync()
+				continue
+			}
+			return Frame{}, &FrameSizeError{size, f.header.size, f.opts.MaxFrameSize}
+		}
+
+		if _, err := f.r.Discard(f.header.size); err != nil {
+			return Frame{}, err
+		}
+		payload := make([]byte, size-int64(f.header.size))
+		if _, err := io.ReadFull(f.r, payload); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return Frame{}, err
+		}
+		return Frame{Header: header, Payload: payload}, nil
+	}
+}
+
+// frameSize returns the frame size including the header for the length value
+// and whether it is within the limits
+func (f *Framer) frameSize(length interface{}) (int64, bool) {
+	var size int64
+	value := reflect.ValueOf(length)
+	if value.CanInt() {
+		size = value.Int()
+	} else if value.Uint() > math.MaxInt64 {
+		size = math.MaxInt64
+	} else {
+		size = int64(value.Uint())
+	}
+	if !f.opts.LengthIncludesHeader && size <= math.MaxInt64-int64(f.header.size) {
+		size += int64(f.header.size)
+	}
+	return size, size >= int64(f.header.size) && size <= int64(f.opts.MaxFrameSize)
+}
+
+// resync discards the first byte and the following ones up to the next possible start of the magic
+func (f *Framer) resync() {
+	skip := 1
+	buffered, _ := f.r.Peek(f.r.Buffered())
+	if len(f.opts.Magic) > 0 && len(buffered) > 1 {
+		if i := bytes.IndexByte(buffered[1:], f.opts.Magic[0]); i >= 0 {
+			skip += i
+		} else {
+			skip = len(buffered)
+		}
+	}
+	n, _ := f.r.Discard(skip)
+	f.skipped += int64(n)
+}
@@ -0,0 +1,120 @@
+package pystruct
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"testing/iotest"
+)
+
+func packFrame(t *testing.T, header PyStruct, values []interface{}, payload string) []byte {
+	t.Helper()
+	frame, err := header.Pack(values...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(frame, payload...)
+}
+
+func TestFramer(t *testing.T) {
+	header, _ := NewStruct("<BH")
+	var stream []byte
+	stream = append(stream, packFrame(t, header, []interface{}{uint8(1), uint16(5)}, "hello")...)
+	stream = append(stream, packFrame(t, header, []interface{}{uint8(2), uint16(0)}, "")...)
+
+	f, err := NewFramer(iotest.OneByteReader(bytes.NewReader(stream)), header, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Frame{
+		{Header: []interface{}{uint8(1), uint16(5)}, Payload: []byte("hello")},
+		{Header: []interface{}{uint8(2), uint16(0)}, Payload: []byte{}},
+	}
+	for _, e := range expected {
+		frame, err := f.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(frame, e) {
+			t.Errorf("Expected: %v\nActual: %v\n", e, frame)
+		}
+	}
+	if _, err := f.Next(); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+
+	f, _ = NewFramer(bytes.NewReader(stream[:6]), header, 1, nil)
+	if _, err := f.Next(); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected io.ErrUnexpectedEOF, got %v", err)
+	}
+	f, _ = NewFramer(bytes.NewReader(stream[:2]), header, 1, nil)
+	if _, err := f.Next(); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected io.ErrUnexpectedEOF, got %v", err)
+	}
+
+	f, _ = NewFramer(bytes.NewReader(stream), header, 1, &FramerOptions{MaxFrameSize: 5})
+	var sizeErr *FrameSizeError
+	if _, err := f.Next(); !errors.As(err, &sizeErr) || sizeErr.Size != 8 {
+		t.Errorf("Expected frame size error, got %v", err)
+	}
+
+	included := packFrame(t, header, []interface{}{uint8(1), uint16(5)}, "hi")
+	f, _ = NewFramer(bytes.NewReader(included), header, 1, &FramerOptions{LengthIncludesHeader: true})
+	if frame, err := f.Next(); err != nil || string(frame.Payload) != "hi" {
+		t.Errorf("Unexpected frame %v, %v", frame, err)
+	}
+	f, _ = NewFramer(bytes.NewReader(stream[8:]), header, 1, &FramerOptions{LengthIncludesHeader: true})
+	if _, err := f.Next(); !errors.As(err, &sizeErr) {
+		t.Errorf("Expected frame size error for a length shorter than the header, got %v", err)
+	}
+}
+
+func TestFramerMagic(t *testing.T) {
+	header, _ := NewStruct(">2sH")
+	magic := []byte{0xaa, 0x55}
+	var stream []byte
+	stream = append(stream, 0x01, 0xaa, 0x02)
+	stream = append(stream, packFrame(t, header, []interface{}{"\xaa\x55", uint16(3)}, "one")...)
+	// corrupt magic and a frame exceeding the limit
+	stream = append(stream, 0xaa, 0x00, 0x00, 0x01)
+	stream = append(stream, packFrame(t, header, []interface{}{"\xaa\x55", uint16(1000)}, "")...)
+	stream = append(stream, packFrame(t, header, []interface{}{"\xaa\x55", uint16(3)}, "two")...)
+
+	f, err := NewFramer(iotest.HalfReader(bytes.NewReader(stream)), header, 1, &FramerOptions{Magic: magic, MaxFrameSize: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, payload := range []string{"one", "two"} {
+		frame, err := f.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(frame.Payload) != payload {
+			t.Errorf("Expected payload %q, got %q", payload, frame.Payload)
+		}
+	}
+	if _, err := f.Next(); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+	if f.Skipped() != 3+4+4 {
+		t.Errorf("Expected %d skipped bytes, got %d", 3+4+4, f.Skipped())
+	}
+}
+
+func TestNewFramerErrors(t *testing.T) {
+	header, _ := NewStruct("<4sfH")
+	if _, err := NewFramer(bytes.NewReader(nil), header, 1, nil); err == nil {
+		t.Error("Expected error for a float length")
+	}
+	if _, err := NewFramer(bytes.NewReader(nil), header, 3, nil); err == nil {
+		t.Error("Expected error for index out of range")
+	}
+	if _, err := NewFramer(bytes.NewReader(nil), header, 2, &FramerOptions{Magic: make([]byte, 11)}); err == nil {
+		t.Error("Expected error for a magic longer than the header")
+	}
+	if _, err := NewFramer(bytes.NewReader(nil), header, 2, &FramerOptions{MaxFrameSize: 4}); err == nil {
+		t.Error("Expected error for a limit smaller than the header")
+	}
+}
@@ -0,0 +1,178 @@
+package pystruct
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+// maxFuzzSize limits the struct size used by the fuzz targets,
+// huge repeat counts are valid formats, but packing them only exhausts memory
+const maxFuzzSize = 1 << 12
+
+// fuzzSeeds are the formats and buffers used by the tests
+var fuzzSeeds = []struct {
+	format string
+	buffer []byte
+}{
+	{"<3sf", []byte{97, 98, 99, 100, 101, 102, 103}},
+	{"3sf", []byte{97, 98, 99, 100, 101, 102, 103}},
+	{"3<sf", []byte{97, 98, 99, 100, 101, 102, 103}},
+	{"<3si", []byte{97, 98, 99, 100, 101, 102, 103}},
+	{"<3s i", []byte{97, 98, 99, 100, 101, 102, 103}},
+	{"<10s 2b d", []byte{97, 98, 99, 100, 101, 102, 103, 97, 98, 99, 100, 101, 102, 103, 102, 103, 100, 101, 102, 103}},
+	{"<10s2bd", []byte{0, 0, 97, 98, 99, 100, 101, 102, 103, 97, 98, 99, 100, 101, 102, 103, 102, 103, 100, 101, 102, 103}},
+	{"<bh", []byte{1, 2, 0, 3, 4, 0, 0xff}},
+	{"@bhbibqbd", make([]byte, 40)},
+	{"!c?HQ2fd", []byte{0xff, 2, 0xff, 0xff, 0x7f, 0xf8, 0, 0, 0, 0, 0, 1, 0x7f, 0xc0, 0, 0, 0x80, 0, 0, 0}},
+	{">3v6VoO", make([]byte, 41)},
+	{"", nil},
+}
+
+// sameValues compares unpacked values, NaN floats are equal to each other and big integers are compared by value
+func sameValues(a, b []interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		switch x := a[i].(type) {
+		case float32:
+			if y, ok := b[i].(float32); ok && math.IsNaN(float64(x)) && math.IsNaN(float64(y)) {
+				continue
+			}
+		case float64:
+			if y, ok := b[i].(float64); ok && math.IsNaN(x) && math.IsNaN(y) {
+				continue
+			}
+		case *big.Int: // 'o', 'v' and 'V' values
+			if y, ok := b[i].(*big.Int); ok && x.Cmp(y) == 0 {
+				continue
+			}
+		}
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func FuzzPackUnpack(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed.format, seed.buffer)
+	}
+
+	f.Fuzz(func(t *testing.T, format string, data []byte) {
+		s, err := NewStruct(format)
+		if err != nil || s.Size() > maxFuzzSize {
+			return
+		}
+
+		buffer := make([]byte, s.Size())
+		copy(buffer, data)
+
+		values, err := s.Unpack(buffer)
+		if err != nil {
+			t.Fatalf("%q: unpack %x: %s", format, buffer, err)
+		}
+
+		packed, err := s.Pack(values...)
+		if err != nil {
+			t.Fatalf("%q: pack %v: %s", format, values, err)
+		}
+		if len(packed) != s.Size() {
+			t.Fatalf("%q: packed %d bytes, expected %d", format, len(packed), s.Size())
+		}
+
+		repacked, err := s.Unpack(packed)
+		if err != nil {
+			t.Fatalf("%q: unpack %x: %s", format, packed, err)
+		}
+		if !sameValues(values, repacked) {
+			t.Fatalf("%q: round trip: %v != %v", format, values, repacked)
+		}
+	})
+}
+
+func FuzzUnpackFrom(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed.format, seed.buffer, 0)
+		f.Add(seed.format, seed.buffer, 2)
+		f.Add(seed.format, seed.buffer, -1)
+	}
+
+	f.Fuzz(func(t *testing.T, format string, buffer []byte, offset int) {
+		s, err := NewStruct(format)
+		if err != nil {
+			return
+		}
+
+		values, n, errN := s.UnpackFromN(buffer, offset)
+		if errN == nil {
+			if n != s.Size() {
+				t.Fatalf("%q: consumed %d bytes, expected %d", format, n, s.Size())
+			}
+			if err := s.PackInto(buffer, offset, values...); err != nil {
+				t.Fatalf("%q: pack into %d: %s", format, offset, err)
+			}
+		}
+
+		if _, err := s.UnpackFrom(buffer, offset); (err == nil) != (errN == nil) {
+			t.Fatalf("%q: UnpackFrom and UnpackFromN disagree at offset %d", format, offset)
+		}
+		s.Unpack(buffer)
+
+		iterator, errs := s.IterUnpack(buffer)
+		for range iterator {
+		}
+		for range errs {
+		}
+	})
+}
+
+// FuzzExported checks that the exported functions never panic
+func FuzzExported(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed.format, seed.buffer, 0)
+		f.Add(seed.format, seed.buffer, -3)
+	}
+
+	f.Fuzz(func(t *testing.T, format string, buffer []byte, offset int) {
+		if size, err := CalcSize(format); err == nil && size > maxFuzzSize {
+			return
+		}
+		values := []interface{}{string(buffer), offset, int8(offset), uint16(offset), float32(offset), nil}
+
+		CalcSize(format)
+		Pack(format, values...)
+		AppendPack(format, buffer, values...)
+		PackInto(format, buffer, offset, values...)
+		Unpack(format, buffer)
+		UnpackFrom(format, buffer, offset)
+		UnpackFromN(format, buffer, offset)
+
+		iterator, errs := IterUnpack(format, buffer)
+		for range iterator {
+		}
+		for range errs {
+		}
+
+		if offset >= 0 && offset < maxFuzzSize {
+			PackIntoGrow(format, buffer, offset, values...)
+		}
+
+		CalcSize_old(format)
+		Pack_old(format, values...)
+		Unpack_old(format, buffer)
+		UnpackFrom_old(format, buffer, offset)
+
+		iterator, errs = IterUnpack_old(format, buffer)
+		for range iterator {
+		}
+		for range errs {
+		}
+
+		if offset >= 0 && offset < maxFuzzSize {
+			PackInto_old(format, buffer, offset, values...)
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package pystruct
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// itemAs returns the i-th value as T, named types of the same kind as the value are accepted
+func itemAs[T any](values []interface{}, i int) (T, error) {
+	var zero T
+	if v, ok := values[i].(T); ok {
+		return v, nil
+	}
+	target := reflect.TypeOf(&zero).Elem()
+	value := reflect.ValueOf(values[i])
+	if target.Kind() == value.Kind() && value.Type().ConvertibleTo(target) {
+		return value.Convert(target).Interface().(T), nil
+	}
+	return zero, fmt.Errorf("struct.error: item %d is %T, not %s", i, values[i], target)
+}
+
+// unpackItems unpacks the buffer if the format has exactly n items
+func unpackItems(s PyStruct, buffer []byte, n int) ([]interface{}, error) {
+	if s.items_num-s.omitted() != n {
+		return nil, fmt.Errorf("struct.error: format %q has %d items, expected %d", s.format, s.items_num-s.omitted(), n)
+	}
+	return s.Unpack(buffer)
+}
+
+// UnpackAs unpacks the buffer of a single item format as a value of type T.
+// The buffer’s size in bytes must match the size required by the format, as reflected by CalcSize().
+func UnpackAs[T any](s PyStruct, buffer []byte) (T, error) {
+	var a T
+	values, err := unpackItems(s, buffer, 1)
+	if err != nil {
+		return a, err
+	}
+	return itemAs[T](values, 0)
+}
+
+// Unpack2 unpacks the buffer of a two items format as values of types A and B
+func Unpack2[A, B any](s PyStruct, buffer []byte) (a A, b B, err error) {
+	values, err := unpackItems(s, buffer, 2)
+	if err != nil {
+		return a, b, err
+	}
+	if a, err = itemAs[A](values, 0); err != nil {
+		return a, b, err
+	}
+	b, err = itemAs[B](values, 1)
+	return a, b, err
+}
+
+// Unpack3 unpacks the buffer of a three items format as values of types A, B and C
+func Unpack3[A, B, C any](s PyStruct, buffer []byte) (a A, b B, c C, err error) {
+	values, err := unpackItems(s, buffer, 3)
+	if err != nil {
+		return a, b, c, err
+	}
+	if a, err = itemAs[A](values, 0); err != nil {
+		return a, b, c, err
+	}
+	if b, err = itemAs[B](values, 1); err != nil {
+		return a, b, c, err
+	}
+	c, err = itemAs[C](values, 2)
+	return a, b, c, err
+}
+
+// Unpack4 unpacks the buffer of a four items format as values of types A, B, C and D
+func Unpack4[A, B, C, D any](s PyStruct, buffer []byte) (a A, b B, c C, d D, err error) {
+	values, err := unpackItems(s, buffer, 4)
+	if err != nil {
+		return a, b, c, d, err
+	}
+	if a, err = itemAs[A](values, 0); err != nil {
+		return a, b, c, d, err
+	}
+	if b, err = itemAs[B](values, 1); err != nil {
+		return a, b, c, d, err
+	}
+	if c, err = itemAs[C](values, 2); err != nil {
+		return a, b, c, d, err
+	}
+	d, err = itemAs[D](values, 3)
+	return a, b, c, d, err
+}
@@ -0,0 +1,103 @@
+package pystruct
+
+import "testing"
+
+func TestUnpackAs(t *testing.T) {
+	s, err := NewStruct("<H")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := UnpackAs[uint16](s, []byte{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 0x0201 {
+		t.Errorf("Expected: %d, Actual: %d", 0x0201, v)
+	}
+
+	type port uint16
+	p, err := UnpackAs[port](s, []byte{0x50, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != 80 {
+		t.Errorf("Expected: 80, Actual: %d", p)
+	}
+
+	if _, err := UnpackAs[int16](s, []byte{1, 2}); err == nil {
+		t.Error("expected error for a type mismatch")
+	}
+	if _, err := UnpackAs[uint16](s, []byte{1}); err == nil {
+		t.Error("expected error for a short buffer")
+	}
+
+	pair, err := NewStruct("<2H")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UnpackAs[uint16](pair, []byte{1, 2, 3, 4}); err == nil {
+		t.Error("expected error for a multiple items format")
+	}
+
+	// values of the same kind aren't always convertible
+	raw, _ := NewStruct("<16s")
+	integer, _ := raw.WithBigInt(0, false)
+	if _, err := UnpackAs[*int](integer, make([]byte, 16)); err == nil {
+		t.Error("expected error for a *big.Int unpacked as a pointer")
+	}
+	wide, _ := raw.WithUint128(0)
+	if _, err := UnpackAs[struct{ A int }](wide, make([]byte, 16)); err == nil {
+		t.Error("expected error for a Uint128 unpacked as a struct")
+	}
+}
+
+func TestUnpackN(t *testing.T) {
+	s, err := NewStruct("<3sbHd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer, err := s.Pack("abc", int8(-1), uint16(2), 1.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	label, kind, length, value, err := Unpack4[string, int8, uint16, float64](s, buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if label != "abc" || kind != -1 || length != 2 || value != 1.5 {
+		t.Errorf("wrong values: %q %d %d %f", label, kind, length, value)
+	}
+
+	if _, _, _, _, err := Unpack4[string, int8, uint16, float32](s, buffer); err == nil {
+		t.Error("expected error for a type mismatch")
+	}
+	if _, _, _, err := Unpack3[string, int8, uint16](s, buffer); err == nil {
+		t.Error("expected error for a wrong number of items")
+	}
+
+	pair, err := NewStruct("<bq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b, err := Unpack2[int8, interface{}](pair, make([]byte, 9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != 0 || b != int64(0) {
+		t.Errorf("wrong values: %v %v", a, b)
+	}
+
+	triple, err := NewStruct("<?cf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	flag, char, f, err := Unpack3[bool, rune, float32](triple, []byte{1, 'x', 0, 0, 0x80, 0x3f})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !flag || char != 'x' || f != 1 {
+		t.Errorf("wrong values: %v %c %f", flag, char, f)
+	}
+}
@@ -0,0 +1,337 @@
+// Package ksy converts PyStruct layouts from and to Kaitai Struct (.ksy) specifications.
+//
+// Only a sequence of fixed size primitive fields can be represented by a PyStruct:
+// integers, floats, fixed size strings and byte arrays, magic contents
+// and fixed repeat counts of numeric fields. Any other construct is reported as an error.
+package ksy
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+
+	pystruct "github.com/o-murphy/pystruct-go"
+	"gopkg.in/yaml.v3"
+)
+
+type spec struct {
+	Meta meta   `yaml:"meta"`
+	Seq  []attr `yaml:"seq"`
+}
+
+type meta struct {
+	ID     string `yaml:"id"`
+	Endian string `yaml:"endian,omitempty"`
+}
+
+type attr struct {
+	ID         string      `yaml:"id"`
+	Type       string      `yaml:"type,omitempty"`
+	Size       interface{} `yaml:"size,omitempty"`
+	Contents   interface{} `yaml:"contents,omitempty"`
+	Encoding   string      `yaml:"encoding,omitempty"`
+	Repeat     string      `yaml:"repeat,omitempty"`
+	RepeatExpr interface{} `yaml:"repeat-expr,omitempty"`
+	Doc        string      `yaml:"doc,omitempty"`
+}
+
+// supportedKeys are the keys of a seq attribute understood by Import
+var supportedKeys = map[string]bool{
+	"id": true, "type": true, "size": true, "contents": true, "encoding": true,
+	"repeat": true, "repeat-expr": true, "doc": true, "doc-ref": true,
+}
+
+// topLevelKeys are the top level keys of a specification understood by Import
+var topLevelKeys = map[string]bool{
+	"meta": true, "seq": true, "doc": true, "doc-ref": true,
+}
+
+var identifierRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+type primitive struct {
+	format rune
+	size   int
+}
+
+var primitives = map[string]primitive{
+	"u1": {'B', 1}, "s1": {'b', 1},
+	"u2": {'H', 2}, "s2": {'h', 2},
+	"u4": {'I', 4}, "s4": {'i', 4},
+	"u8": {'Q', 8}, "s8": {'q', 8},
+	"f4": {'f', 4}, "f8": {'d', 8},
+}
+
+var exportTypes = map[string]string{
+	"b": "s1", "B": "u1", "?": "u1",
+	"h": "s2", "H": "u2",
+	"i": "s4", "I": "u4", "l": "s4", "L": "u4",
+	"q": "s8", "Q": "u8",
+	"f": "f4", "d": "f8",
+}
+
+// Import reads a .ksy specification and returns a PyStruct with the seq attributes as named fields
+func Import(data []byte) (pystruct.PyStruct, error) {
+	var raw map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return pystruct.PyStruct{}, fmt.Errorf("ksy: %w", err)
+	}
+	for key := range raw {
+		if !topLevelKeys[key] {
+			return pystruct.PyStruct{}, fmt.Errorf("ksy: %q is not supported", key)
+		}
+	}
+
+	var rawSeq []map[string]interface{}
+	if node, ok := raw["seq"]; ok {
+		if err := node.Decode(&rawSeq); err != nil {
+			return pystruct.PyStruct{}, fmt.Errorf("ksy: seq: %w", err)
+		}
+	}
+	for i, fields := range rawSeq {
+		for key := range fields {
+			if !supportedKeys[key] {
+				return pystruct.PyStruct{}, fmt.Errorf("ksy: seq[%d] %v: %q is not supported", i, fields["id"], key)
+			}
+		}
+	}
+
+	var ksy spec
+	if err := yaml.Unmarshal(data, &ksy); err != nil {
+		return pystruct.PyStruct{}, fmt.Errorf("ksy: %w", err)
+	}
+	if ksy.Meta.Endian != "" && ksy.Meta.Endian != "le" && ksy.Meta.Endian != "be" {
+		return pystruct.PyStruct{}, fmt.Errorf("ksy: meta/endian %q is not supported", ksy.Meta.Endian)
+	}
+
+	var format bytes.Buffer
+	names := make([]string, 0, len(ksy.Seq))
+	endian := ""
+
+	for i, a := range ksy.Seq {
+		if a.ID == "" {
+			return pystruct.PyStruct{}, fmt.Errorf("ksy: seq[%d]: id is required", i)
+		}
+
+		group, fieldEndian, err := importAttr(a)
+		if err != nil {
+			return pystruct.PyStruct{}, fmt.Errorf("ksy: seq[%d] %s: %w", i, a.ID, err)
+		}
+
+		if fieldEndian == "?" {
+			if ksy.Meta.Endian == "" {
+				return pystruct.PyStruct{}, fmt.Errorf("ksy: seq[%d] %s: endianness is not specified", i, a.ID)
+			}
+			fieldEndian = ksy.Meta.Endian
+		}
+		if fieldEndian != "" {
+			if endian != "" && endian != fieldEndian {
+				return pystruct.PyStruct{}, fmt.Errorf("ksy: seq[%d] %s: mixed endianness is not supported", i, a.ID)
+			}
+			endian = fieldEndian
+		}
+
+		format.WriteString(group)
+		names = append(names, a.ID)
+	}
+
+	prefix := "<"
+	if endian == "be" || (endian == "" && ksy.Meta.Endian == "be") {
+		prefix = ">"
+	}
+	return pystruct.NewStructWithNames(prefix+format.String(), names...)
+}
+
+// importAttr returns the format group of the attribute and its endianness:
+// "le" or "be" if set by the type, "?" if the type requires the default one, "" if it is not relevant
+func importAttr(a attr) (string, string, error) {
+	if a.Repeat != "" && a.Repeat != "expr" {
+		return "", "", fmt.Errorf("repeat: %s is not supported", a.Repeat)
+	}
+	if a.Repeat == "expr" && a.RepeatExpr == nil {
+		return "", "", fmt.Errorf("repeat: expr requires an integer repeat-expr")
+	}
+	if a.Repeat == "" && a.RepeatExpr != nil {
+		return "", "", fmt.Errorf("repeat-expr without repeat: expr")
+	}
+
+	size, err := intValue(a.Size, "size")
+	if err != nil {
+		return "", "", err
+	}
+	count, err := intValue(a.RepeatExpr, "repeat-expr")
+	if err != nil {
+		return "", "", err
+	}
+
+	switch {
+	case a.Contents != nil:
+		if a.Type != "" || a.Size != nil || a.Repeat != "" {
+			return "", "", fmt.Errorf("contents can't be combined with type, size or repeat")
+		}
+		size, err := contentsSize(a.Contents)
+		if err != nil {
+			return "", "", err
+		}
+		return fmt.Sprintf("%ds", size), "", nil
+
+	case a.Type == "" || a.Type == "str":
+		if size == nil {
+			return "", "", fmt.Errorf("integer size is required")
+		}
+		if a.Repeat != "" {
+			return "", "", fmt.Errorf("repeated byte arrays are not supported")
+		}
+		return fmt.Sprintf("%ds", *size), "", nil
+	}
+
+	if a.Size != nil {
+		return "", "", fmt.Errorf("size can't be used with type %s", a.Type)
+	}
+
+	name, endian := a.Type, "?"
+	if len(name) == 4 && (name[2:] == "le" || name[2:] == "be") {
+		name, endian = name[:2], name[2:]
+	}
+	prim, ok := primitives[name]
+	if !ok {
+		return "", "", fmt.Errorf("type %s is not supported", a.Type)
+	}
+	if prim.size == 1 {
+		if endian != "?" {
+			return "", "", fmt.Errorf("type %s is not supported", a.Type)
+		}
+		endian = ""
+	}
+
+	if count == nil || *count == 1 {
+		return string(prim.format), endian, nil
+	}
+	return fmt.Sprintf("%d%c", *count, prim.format), endian, nil
+}
+
+// intValue returns the non-negative integer value of the key, nil if it is not set
+func intValue(value interface{}, key string) (*int, error) {
+	if value == nil {
+		return nil, nil
+	}
+	n, ok := value.(int)
+	if !ok {
+		return nil, fmt.Errorf("%s %v is not supported, only integer constants are", key, value)
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("negative %s %d", key, n)
+	}
+	return &n, nil
+}
+
+// contentsSize returns the size of the magic contents, given as a string or as an array of bytes and strings
+func contentsSize(contents interface{}) (int, error) {
+	switch v := contents.(type) {
+	case string:
+		return len(v), nil
+	case []interface{}:
+		size := 0
+		for _, item := range v {
+			switch b := item.(type) {
+			case int:
+				if b < 0 || b > 255 {
+					return 0, fmt.Errorf("contents byte %d is out of range", b)
+				}
+				size++
+			case string:
+				size += len(b)
+			default:
+				return 0, fmt.Errorf("contents item %v is not supported", item)
+			}
+		}
+		return size, nil
+	}
+	return 0, fmt.Errorf("contents %v is not supported", contents)
+}
+
+// nativeEndian returns the ksy endianness of the native byte order
+func nativeEndian() string {
+	if probe, err := pystruct.Pack("=H", uint16(1)); err == nil && probe[0] == 1 {
+		return "le"
+	}
+	return "be"
+}
+
+// Export writes the layout of s as a .ksy specification with the given id.
+// Unnamed fields get "field_<index>" ids, 'c' fields are exported as 1 byte arrays
+// and '?' fields as u1 integers.
+func Export(s pystruct.PyStruct, id string) ([]byte, error) {
+	if !identifierRegexp.MatchString(id) {
+		return nil, fmt.Errorf("ksy: invalid id %q", id)
+	}
+
+	layout := s.Describe()
+	ksy := spec{Meta: meta{ID: id}}
+
+	switch layout.ByteOrder {
+	case "<":
+		ksy.Meta.Endian = "le"
+	case ">", "!":
+		ksy.Meta.Endian = "be"
+	default:
+		ksy.Meta.Endian = nativeEndian()
+	}
+
+	offset := 0
+	for i, field := range layout.Fields {
+		if field.Offset != offset {
+			return nil, fmt.Errorf("ksy: field %d: native alignment padding is not supported", i)
+		}
+		if field.ByteOrder != "" {
+			return nil, fmt.Errorf("ksy: field %d: mixed endianness is not supported", i)
+		}
+		offset += field.Size
+
+		a := attr{ID: field.Name}
+		if a.ID == "" {
+			a.ID = fmt.Sprintf("field_%d", i)
+		}
+		if !identifierRegexp.MatchString(a.ID) {
+			return nil, fmt.Errorf("ksy: field %d: invalid id %q", i, a.ID)
+		}
+
+		switch field.Format {
+		case "s":
+			a.Size = field.Count
+		case "c":
+			a.Size = 1
+			a.Repeat, a.RepeatExpr = repeatExpr(field.Count)
+			if a.Repeat != "" {
+				return nil, fmt.Errorf("ksy: field %d: repeated 'c' is not supported, use 's'", i)
+			}
+		default:
+			a.Type = exportTypes[field.Format]
+			a.Repeat, a.RepeatExpr = repeatExpr(field.Count)
+			if field.Format == "?" {
+				a.Doc = "bool"
+			}
+		}
+		ksy.Seq = append(ksy.Seq, a)
+	}
+	if offset != layout.Size {
+		return nil, fmt.Errorf("ksy: native alignment padding is not supported")
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(ksy); err != nil {
+		return nil, fmt.Errorf("ksy: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("ksy: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+func repeatExpr(count int) (string, interface{}) {
+	if count == 1 {
+		return "", nil
+	}
+	return "expr", count
+}
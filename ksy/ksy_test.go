@@ -0,0 +1,154 @@
+package ksy
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	pystruct "github.com/o-murphy/pystruct-go"
+)
+
+func importFile(t *testing.T, name string) (pystruct.PyStruct, error) {
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Import(data)
+}
+
+func TestImport(t *testing.T) {
+	cases := []struct {
+		file   string
+		format string
+		names  []string
+	}{
+		{
+			"header.ksy",
+			"<4sBbH3hQf6s2s",
+			[]string{"magic", "version", "flags", "length", "readings", "timestamp", "scale", "label", "reserved"},
+		},
+		{
+			"big_endian.ksy",
+			">BId",
+			[]string{"kind", "seq_no", "value"},
+		},
+	}
+
+	for _, c := range cases {
+		s, err := importFile(t, c.file)
+		if err != nil {
+			t.Errorf("%s: %s", c.file, err)
+			continue
+		}
+		if s.Format() != c.format {
+			t.Errorf("%s: expected format %q, got %q", c.file, c.format, s.Format())
+		}
+		if !reflect.DeepEqual(s.Names(), c.names) {
+			t.Errorf("%s: expected names %v, got %v", c.file, c.names, s.Names())
+		}
+	}
+}
+
+func TestImportUnsupported(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "unsupported_*.ksy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no unsupported fixtures")
+	}
+
+	for _, file := range files {
+		_, err := importFile(t, filepath.Base(file))
+		if err == nil {
+			t.Errorf("%s: expected error", file)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), "ksy: ") {
+			t.Errorf("%s: unexpected error: %s", file, err)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	s, err := pystruct.NewStructWithNames("<4sB3hd", "magic", "version", "readings", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := Export(s, "record")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `meta:
+  id: record
+  endian: le
+seq:
+  - id: magic
+    size: 4
+  - id: version
+    type: u1
+  - id: readings
+    type: s2
+    repeat: expr
+    repeat-expr: 3
+  - id: value
+    type: f8
+`
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nActual:\n%s", expected, data)
+	}
+
+	imported, err := Import(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(imported.Describe(), s.Describe()) {
+		t.Errorf("round trip differs:\n%+v\n%+v", imported.Describe(), s.Describe())
+	}
+}
+
+func TestExportUnnamed(t *testing.T) {
+	s, err := pystruct.NewStruct(">H?c")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := Export(s, "unnamed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"field_0", "field_1", "field_2"} {
+		if !strings.Contains(string(data), "id: "+id) {
+			t.Errorf("missing %s in:\n%s", id, data)
+		}
+	}
+	if !strings.Contains(string(data), "endian: be") {
+		t.Errorf("wrong endianness in:\n%s", data)
+	}
+}
+
+func TestExportErrors(t *testing.T) {
+	padded, err := pystruct.NewStruct("@bi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Export(padded, "padded"); err == nil {
+		t.Error("expected error for native alignment padding")
+	}
+
+	named, err := pystruct.NewStructWithNames("<b", "Bad Name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Export(named, "named"); err == nil {
+		t.Error("expected error for invalid field id")
+	}
+
+	if _, err := Export(named, "Record"); err == nil {
+		t.Error("expected error for invalid spec id")
+	}
+}
@@ -0,0 +1,216 @@
+package pystruct
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Layout describes the binary layout of a compiled PyStruct,
+// it can be serialized to JSON to share it with non-Go tools
+type Layout struct {
+	ByteOrder string        `json:"byte_order"` // byte order character, one of "@", "=", "<", ">", "!"
+	Size      int           `json:"size"`       // total size in bytes, as reflected by CalcSize()
+	Fields    []FieldLayout `json:"fields"`
+}
+
+// FieldLayout describes a single format group of the struct, like "3s" or "2h"
+type FieldLayout struct {
+	Name      string `json:"name,omitempty"`       // field name, if the struct has named fields
+	Offset    int    `json:"offset"`               // byte offset of the field in the packed struct
+	Size      int    `json:"size"`                 // size of the field in bytes, including repeats
+	Format    string `json:"format"`               // format character
+	CType     string `json:"c_type"`               // C type name, like "Int24" for an N-byte integer 's' field
+	GoType    string `json:"go_type"`              // Go type of the unpacked value, as set by the enum, scaled and integer modifiers
+	Count     int    `json:"count"`                // repeat count, length in bytes for 's'
+	ByteOrder string `json:"byte_order,omitempty"` // byte order character, if it differs from the struct one
+}
+
+// Describe returns the layout of the struct. N-byte integer fields keep their 's' format,
+// so NewStructFromLayout loads them back as byte strings: their modifiers must be applied again.
+// The layout of a struct composed by Concat or Embed can't be loaded back by NewStructFromLayout
+// if its fields use different byte orders, or if a nested struct is padded unlike the flat format.
+func (s *PyStruct) Describe() Layout {
+	layout := Layout{
+		ByteOrder: string(getOrderChar(s.format)),
+		Size:      s.size,
+		Fields:    make([]FieldLayout, 0, len(s.groups)),
+	}
+	for i, group := range s.groups {
+		layout.Fields = append(layout.Fields, FieldLayout{
+			Name:   s.fieldName(i),
+			Offset: group.offset,
+			Size:   group.number * group.alignment,
+			Format: string(group.format),
+			CType:  cFormatStringMap[group.format],
+			GoType: goTypeStringMap[group.format],
+			Count:  group.number,
+		})
+		if f, ok := s.bigInt(group.first); ok && group.format == tString {
+			layout.Fields[i].CType = f.cType()
+		}
+		if typ := s.groupValueType(group); typ != nil {
+			layout.Fields[i].GoType = typ.String()
+		}
+		if group.orderChar != getOrderChar(s.format) {
+			layout.Fields[i].ByteOrder = string(group.orderChar)
+		}
+	}
+	return layout
+}
+
+// groupValueType returns the Go type of the values of the group set by the enum, scaled or integer modifiers,
+// nil if they don't set the same type for all of its items
+func (s *PyStruct) groupValueType(group formatGroup) reflect.Type {
+	count := group.number
+	if group.format == tString {
+		count = 1
+	}
+	var typ reflect.Type
+	for i := group.first; i < group.first+count; i++ {
+		t := s.valueType(i)
+		if e, ok := s.enum(i); ok {
+			t = e.typ
+		}
+		if t == nil || (typ != nil && t != typ) {
+			return nil
+		}
+		typ = t
+	}
+	return typ
+}
+
+// Format returns the format string described by the layout
+func (l Layout) Format() string {
+	var sb strings.Builder
+	sb.WriteString(l.ByteOrder)
+	for _, field := range l.Fields {
+		if field.Count != 1 {
+			sb.WriteString(strconv.Itoa(field.Count))
+		}
+		sb.WriteString(field.Format)
+	}
+	return sb.String()
+}
+
+// names returns the field names, nil if none of the fields is named
+func (l Layout) names() []string {
+	for _, field := range l.Fields {
+		if field.Name != "" {
+			names := make([]string, len(l.Fields))
+			for i, field := range l.Fields {
+				names[i] = field.Name
+			}
+			return names
+		}
+	}
+	return nil
+}
+
+// NewStructFromLayout(layout) --> compiled PyStruct object
+// The offsets and sizes of the layout are verified against the compiled struct
+func NewStructFromLayout(layout Layout) (PyStruct, error) {
+	if len(layout.ByteOrder) != 1 {
+		return PyStruct{}, fmt.Errorf("struct.error: bad byte order %q in layout", layout.ByteOrder)
+	}
+	if _, ok := cOrderMap[rune(layout.ByteOrder[0])]; !ok {
+		return PyStruct{}, fmt.Errorf("struct.error: bad byte order %q in layout", layout.ByteOrder)
+	}
+	for i, field := range layout.Fields {
+		if len(field.Format) != 1 {
+			return PyStruct{}, fmt.Errorf("struct.error: bad format %q of field %d in layout", field.Format, i)
+		}
+		if _, ok := cFormatMap[cFormatRune(field.Format[0])]; !ok {
+			return PyStruct{}, fmt.Errorf("struct.error: bad format %q of field %d in layout", field.Format, i)
+		}
+		if field.Count < 0 {
+			return PyStruct{}, fmt.Errorf("struct.error: negative count of field %d in layout", i)
+		}
+		if field.ByteOrder != "" && field.ByteOrder != layout.ByteOrder {
+			return PyStruct{}, fmt.Errorf("struct.error: byte order %q of field %d differs from the layout one", field.ByteOrder, i)
+		}
+	}
+
+	s, err := NewStruct(layout.Format())
+	if err != nil {
+		return PyStruct{}, err
+	}
+	if names := layout.names(); names != nil {
+		if s, err = NewStructWithNames(layout.Format(), names...); err != nil {
+			return PyStruct{}, err
+		}
+	}
+
+	described := s.Describe()
+	if layout.Size != described.Size {
+		return PyStruct{}, fmt.Errorf("struct.error: layout size %d doesn't match the struct size %d", layout.Size, described.Size)
+	}
+	for i, field := range layout.Fields {
+		if field.Offset != described.Fields[i].Offset || field.Size != described.Fields[i].Size {
+			return PyStruct{}, fmt.Errorf(
+				"struct.error: field %d at offset %d of %d bytes doesn't match the struct field at offset %d of %d bytes",
+				i, field.Offset, field.Size, described.Fields[i].Offset, described.Fields[i].Size,
+			)
+		}
+	}
+	return s, nil
+}
+
+// LoadLayout parses a JSON layout, as produced by json.Marshal(s.Describe()),
+// and returns the compiled PyStruct object
+func LoadLayout(data []byte) (PyStruct, error) {
+	var layout Layout
+	if err := json.Unmarshal(data, &layout); err != nil {
+		return PyStruct{}, err
+	}
+	return NewStructFromLayout(layout)
+}
+
+// Field describes a single value of the struct, as returned by Unpack
+type Field struct {
+	Name   string // name of the format group, if the struct has named fields
+	Group  int    // index of the format group
+	Index  int    // repeat index in the format group, always 0 for 's'
+	Offset int    // byte offset of the value in the packed struct
+	Size   int    // size of the value in bytes
+	Format rune   // format character
+}
+
+// NumItems returns the number of values packed or unpacked by the struct
+func (s *PyStruct) NumItems() int {
+	return s.items_num
+}
+
+// ByteOrder returns the byte order of the struct, the native order for '@' and '=',
+// or nil for a struct composed by Concat or Embed whose fields use different byte orders
+func (s *PyStruct) ByteOrder() binary.ByteOrder {
+	return s.order
+}
+
+// Fields returns the values of the struct in the packing order
+func (s *PyStruct) Fields() []Field {
+	items := s.items()
+	fields := make([]Field, len(items))
+	for i, it := range items {
+		fields[i] = Field{
+			Name:   s.fieldName(it.group),
+			Group:  it.group,
+			Index:  it.index,
+			Offset: it.offset,
+			Size:   it.size,
+			Format: rune(it.format),
+		}
+	}
+	return fields
+}
+
+// OffsetOf returns the byte offset of the i-th value in the packed struct
+func (s *PyStruct) OffsetOf(i int) (int, error) {
+	if i < 0 || i >= s.items_num {
+		return 0, fmt.Errorf("struct.error: item index %d out of range [0, %d)", i, s.items_num)
+	}
+	return s.item(i).offset, nil
+}
@@ -0,0 +1,143 @@
+package pystruct
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+	s, err := NewStruct("@b2h3sd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layout := s.Describe()
+	expected := Layout{
+		ByteOrder: "@",
+		Size:      24,
+		Fields: []FieldLayout{
+			{Offset: 0, Size: 1, Format: "b", CType: "SChar", GoType: "int8", Count: 1},
+			{Offset: 2, Size: 4, Format: "h", CType: "Short", GoType: "int16", Count: 2},
+			{Offset: 6, Size: 3, Format: "s", CType: "String", GoType: "string", Count: 3},
+			{Offset: 16, Size: 8, Format: "d", CType: "Double", GoType: "float64", Count: 1},
+		},
+	}
+
+	if !reflect.DeepEqual(layout, expected) {
+		t.Errorf("Expected: %+v\nActual: %+v\n", expected, layout)
+	}
+}
+
+func TestDescribeValueTypes(t *testing.T) {
+	record, _ := NewStruct("<Bh2h")
+	s, err := WithEnum(record, 0, msgKindNames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, err = s.WithScale(1, 0.01, 0); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = s.WithScale(2, 0.1, 0); err != nil { // only the first item of the group
+		t.Fatal(err)
+	}
+
+	var types []string
+	for _, field := range s.Describe().Fields {
+		types = append(types, field.GoType)
+	}
+	if expected := []string{"pystruct.msgKind", "float64", "int16"}; !reflect.DeepEqual(types, expected) {
+		t.Errorf("Expected: %v\nActual: %v\n", expected, types)
+	}
+}
+
+func TestLoadLayout(t *testing.T) {
+	for _, format := range []string{"<3sf", ">HhIiQq", "@bhbibqbd", "!0s?c", "=10s2bd"} {
+		s, err := NewStruct(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := json.Marshal(s.Describe())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		loaded, err := LoadLayout(data)
+		if err != nil {
+			t.Errorf("%q: %s\n%s", format, err, data)
+			continue
+		}
+		if loaded.Size() != s.Size() || !reflect.DeepEqual(loaded.groups, s.groups) || loaded.order != s.order {
+			t.Errorf("%q: loaded struct differs: %s", format, loaded.Format())
+		}
+	}
+}
+
+func TestLoadLayoutErrors(t *testing.T) {
+	cases := []string{
+		`{"byte_order": "<", "size": 7, "fields": [{"offset": 0, "size": 3, "format": "s", "count": 3}, {"offset": 3, "size": 4, "format": "f", "count": 1}]}`,
+		`{"byte_order": "<", "size": 8, "fields": [{"offset": 0, "size": 3, "format": "s", "count": 3}, {"offset": 4, "size": 4, "format": "f", "count": 1}]}`,
+		`{"byte_order": "?", "size": 1, "fields": [{"offset": 0, "size": 1, "format": "b", "count": 1}]}`,
+		`{"byte_order": "<", "size": 1, "fields": [{"offset": 0, "size": 1, "format": "z", "count": 1}]}`,
+		`{"byte_order": "<", "size": 1, "fields": [{"offset": 0, "size": 1, "format": " ", "count": 1}]}`,
+		`{"byte_order": "<", "size": 0, "fields": [{"offset": 0, "size": 0, "format": "b", "count": -1}]}`,
+		`not a json`,
+	}
+
+	for i, data := range cases {
+		_, err := LoadLayout([]byte(data))
+		if i == 0 {
+			if err != nil {
+				t.Errorf("case %d: unexpected error: %s", i, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("case %d: expected error", i)
+		}
+	}
+}
+
+func TestFields(t *testing.T) {
+	s, err := NewStructWithNames("@b2h3sd", "kind", "xy", "label", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Field{
+		{Name: "kind", Group: 0, Index: 0, Offset: 0, Size: 1, Format: 'b'},
+		{Name: "xy", Group: 1, Index: 0, Offset: 2, Size: 2, Format: 'h'},
+		{Name: "xy", Group: 1, Index: 1, Offset: 4, Size: 2, Format: 'h'},
+		{Name: "label", Group: 2, Index: 0, Offset: 6, Size: 3, Format: 's'},
+		{Name: "value", Group: 3, Index: 0, Offset: 16, Size: 8, Format: 'd'},
+	}
+	if fields := s.Fields(); !reflect.DeepEqual(fields, expected) {
+		t.Errorf("Expected: %+v\nActual: %+v\n", expected, fields)
+	}
+	if s.NumItems() != len(expected) {
+		t.Errorf("Expected %d items, got %d", len(expected), s.NumItems())
+	}
+	if s.ByteOrder() != getNativeOrder() {
+		t.Errorf("Expected native byte order, got %v", s.ByteOrder())
+	}
+
+	for i, field := range expected {
+		offset, err := s.OffsetOf(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if offset != field.Offset {
+			t.Errorf("Item %d expected offset %d, got %d", i, field.Offset, offset)
+		}
+	}
+	if _, err := s.OffsetOf(len(expected)); err == nil {
+		t.Error("Expected error for index out of range")
+	}
+
+	be, _ := NewStruct("!H")
+	if be.ByteOrder() != binary.BigEndian {
+		t.Errorf("Expected big endian byte order, got %v", be.ByteOrder())
+	}
+}
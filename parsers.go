@@ -1,11 +1,11 @@
 package pystruct
 
 import (
-	"bytes"
 	"encoding/binary"
 	"fmt"
 	"math"
 	"reflect"
+	"unsafe"
 )
 
 type cOrder rune
@@ -108,6 +108,25 @@ var cFormatStringMap = map[cFormatRune]string{
 	// 'P': "VoidP",
 }
 
+// Go types of the unpacked values
+var goTypeStringMap = map[cFormatRune]string{
+	'c': "rune",
+	'b': "int8",
+	'B': "uint8",
+	'?': "bool",
+	'h': "int16",
+	'H': "uint16",
+	'i': "int32",
+	'I': "uint32",
+	'l': "int32",
+	'L': "uint32",
+	'q': "int64",
+	'Q': "uint64",
+	'f': "float32",
+	'd': "float64",
+	's': "string",
+}
+
 var formatAlignmentMap = map[cFormatRune]int{
 	// 'x': 1,
 	'c': 1, 'b': 1, 'B': 1, '?': 1,
@@ -121,12 +140,29 @@ var formatAlignmentMap = map[cFormatRune]int{
 }
 
 func getNativeOrder() binary.ByteOrder {
-	var nativeEndian binary.ByteOrder
-	if nativeEndian = binary.LittleEndian; nativeEndian == binary.BigEndian {
+	var probe uint16 = 1
+	if *(*byte)(unsafe.Pointer(&probe)) == 1 {
 		return binary.LittleEndian
-	} else {
-		return binary.BigEndian
 	}
+	return binary.BigEndian
+}
+
+// getOrderChar returns the byte order character of the format, '@' if it is omitted
+func getOrderChar(format string) cOrder {
+	if len(format) > 0 {
+		if ord, ok := cOrderMap[rune(format[0])]; ok {
+			return ord
+		}
+	}
+	return tNativeOrderSize
+}
+
+// groupAlignment returns the alignment of the group start, only groups of native alignment ('@') are aligned
+func groupAlignment(group formatGroup) int {
+	if group.orderChar != tNativeOrderSize || group.format == tString || formatAlignmentMap[group.format] < 1 {
+		return 1
+	}
+	return formatAlignmentMap[group.format]
 }
 
 func getOrder(order rune) (binary.ByteOrder, error) {
@@ -194,82 +230,119 @@ func parseValue(buffer []byte, cFmtRune cFormatRune, endian binary.ByteOrder) in
 }
 
 func buildValue(value interface{}, cFmtRune cFormatRune, endian binary.ByteOrder) []byte {
+	if data, ok := appendValue(nil, value, cFmtRune, endian); ok {
+		return data
+	}
+	return nil
+}
 
-	buffer := new(bytes.Buffer)
+// appendString appends value to dst as a char[size], truncating or zero padding it to size bytes
+func appendString(dst []byte, value string, size int) []byte {
+	if len(value) > size {
+		value = value[:size]
+	}
+	dst = append(dst, value...)
+	for i := len(value); i < size; i++ {
+		dst = append(dst, 0)
+	}
+	return dst
+}
+
+// appendValue appends the encoded value to dst,
+// returns false if the value type is not suitable for the format
+func appendValue(dst []byte, value interface{}, cFmtRune cFormatRune, endian binary.ByteOrder) ([]byte, bool) {
+	var scratch [8]byte
+
+	if value == nil {
+		return dst, false
+	}
 	ref_val := reflect.ValueOf(value)
 
 	switch cFmtRune {
-	case tChar, tSChar:
-		switch value.(type) {
+	case tChar:
+		switch v := value.(type) {
+		case rune:
+			return append(dst, byte(v)), true
+		}
+	case tSChar:
+		switch v := value.(type) {
 		case rune:
-			binary.Write(buffer, endian, byte(ref_val.Int()))
+			return append(dst, byte(v)), true
+		case int8:
+			return append(dst, byte(v)), true
 		}
 	case tUChar:
-		switch value.(type) {
+		switch v := value.(type) {
 		case uint8:
-			binary.Write(buffer, endian, byte(ref_val.Uint()))
+			return append(dst, v), true
 		}
 	case tBool:
-		n := 0
-		switch ref_val.Type().Kind() {
+		n := byte(0)
+		switch ref_val.Kind() {
 		case reflect.Bool:
 			if ref_val.Bool() {
 				n = 1
 			}
-			binary.Write(buffer, endian, byte(n))
+			return append(dst, n), true
 		case reflect.Int, reflect.Int16, reflect.Int32, reflect.Int64:
 			if ref_val.Int() > 0 {
 				n = 1
 			}
-			binary.Write(buffer, endian, byte(n))
+			return append(dst, n), true
 		}
 	case tShort:
-		switch ref_val.Type().Kind() {
+		switch ref_val.Kind() {
 		case reflect.Int8, reflect.Int16:
-			binary.Write(buffer, endian, int16(ref_val.Int()))
+			endian.PutUint16(scratch[:2], uint16(ref_val.Int()))
+			return append(dst, scratch[:2]...), true
 		}
 	case tUShort:
-		switch ref_val.Type().Kind() {
+		switch ref_val.Kind() {
 		case reflect.Uint8, reflect.Uint16:
-			binary.Write(buffer, endian, uint16(ref_val.Int()))
+			endian.PutUint16(scratch[:2], uint16(ref_val.Uint()))
+			return append(dst, scratch[:2]...), true
 		}
 	case tInt, tLong:
-		switch ref_val.Type().Kind() {
+		switch ref_val.Kind() {
 		case reflect.Int8, reflect.Int16, reflect.Int32:
-			binary.Write(buffer, endian, int32(ref_val.Int()))
+			endian.PutUint32(scratch[:4], uint32(ref_val.Int()))
+			return append(dst, scratch[:4]...), true
 		}
 	case tUInt, tULong:
-		switch ref_val.Type().Kind() {
+		switch ref_val.Kind() {
 		case reflect.Uint8, reflect.Uint16, reflect.Uint32:
-			binary.Write(buffer, endian, uint32(ref_val.Int()))
+			endian.PutUint32(scratch[:4], uint32(ref_val.Uint()))
+			return append(dst, scratch[:4]...), true
 		}
 	case tLongLong:
-		switch ref_val.Type().Kind() {
+		switch ref_val.Kind() {
 		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			binary.Write(buffer, endian, int64(ref_val.Int()))
+			endian.PutUint64(scratch[:8], uint64(ref_val.Int()))
+			return append(dst, scratch[:8]...), true
 		}
 	case tULongLong:
-		switch ref_val.Type().Kind() {
+		switch ref_val.Kind() {
 		case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			binary.Write(buffer, endian, uint64(ref_val.Int()))
+			endian.PutUint64(scratch[:8], ref_val.Uint())
+			return append(dst, scratch[:8]...), true
 		}
 	case tFloat32:
-		switch ref_val.Type().Kind() {
+		switch ref_val.Kind() {
 		case reflect.Float32, reflect.Float64:
-			binary.Write(buffer, endian, float32(ref_val.Float()))
+			endian.PutUint32(scratch[:4], math.Float32bits(float32(ref_val.Float())))
+			return append(dst, scratch[:4]...), true
 		}
 	case tDouble:
-		switch ref_val.Type().Kind() {
+		switch ref_val.Kind() {
 		case reflect.Float32, reflect.Float64:
-			binary.Write(buffer, endian, float64(ref_val.Float()))
+			endian.PutUint64(scratch[:8], math.Float64bits(ref_val.Float()))
+			return append(dst, scratch[:8]...), true
 		}
-	// TODO:
-	// case PadByte
-	// case Float16:
-	// case CharP:
-	// case VoidP:
-	default:
-		return nil
+		// TODO:
+		// case PadByte
+		// case Float16:
+		// case CharP:
+		// case VoidP:
 	}
-	return buffer.Bytes()
+	return dst, false
 }
@@ -3,36 +3,35 @@ package pystruct
 import (
 	"encoding/binary"
 	"fmt"
-	"regexp"
-	"strconv"
-	"strings"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+	"unicode/utf8"
 )
 
-var formatPattern string = `^([@<>=!])?((\d*[cbBhHiIqQlLfds])+)$`
-var groupPattern string = `(\d*)([cbBhHiIqQlLfds])`
-var formatRegexp *regexp.Regexp
-var groupRegexp *regexp.Regexp
-
-func init() {
-	// Compile the regular expression
-	fmtRe, err := regexp.Compile(formatPattern)
-	if err != nil {
-		panic(fmt.Sprint("Error compiling format regex:", err))
-	}
-	formatRegexp = fmtRe
+// FormatError describes a problem with a struct format string
+// and the byte position in the format string where it was found
+type FormatError struct {
+	Format string // the format string being parsed
+	Pos    int    // byte offset of the problem in Format
+	Msg    string // description of the problem
+}
 
-	// Define the regex for individual groups with separate capture for number and char
-	groupRe, err := regexp.Compile(groupPattern)
-	if err != nil {
-		panic(fmt.Sprint("Error compiling group regex:", err))
-	}
-	groupRegexp = groupRe
+func (e *FormatError) Error() string {
+	return fmt.Sprintf("struct.error: %s at position %d in struct format %q", e.Msg, e.Pos, e.Format)
 }
 
 type formatGroup struct {
 	number    int
 	format    cFormatRune
 	alignment int // cached alignment value
+	padding   int // pad bytes before the group, used with native alignment only
+	offset    int // byte offset of the group in the packed struct
+	first     int // index of the first item of the group
+	order     binary.ByteOrder
+	orderChar cOrder      // byte order character the group was compiled with
+	integer   cFormatRune // 'o', 'O', 'v' or 'V' of an 's' group declared by an integer format character, 0 otherwise
 }
 
 func newFormatGroup(number int, format cFormatRune) formatGroup {
@@ -43,44 +42,86 @@ func newFormatGroup(number int, format cFormatRune) formatGroup {
 	}
 }
 
-func strip(format string) string {
-	return strings.ReplaceAll(format, " ", "")
+// isSpace reports whether c is a whitespace allowed between format characters,
+// same set as CPython's Py_ISSPACE
+func isSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', '\v', '\f':
+		return true
+	}
+	return false
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
 }
 
+// parseFormat parses the format string in a single pass.
+// The optional byte order character is accepted only as the very first character,
+// whitespace is allowed between format characters, but not between a repeat count and its format character.
 func parseFormat(format string) (binary.ByteOrder, []formatGroup, error) {
 	var order binary.ByteOrder = getNativeOrder()
 	var formatGroups []formatGroup
 
-	format = strip(format)
-
-	// Find the entire match with submatches
-	matches := formatRegexp.FindStringSubmatch(format)
-	if len(matches) == 0 {
-		return nil, nil, fmt.Errorf("struct.error: Unexpected struct format %s", format)
-	}
-
-	// Extract and print the prefix if present
-	if prefix := matches[1]; prefix != "" {
-		order, _ = getOrder(rune(prefix[0]))
+	pos := 0
+	if len(format) > 0 {
+		if ord, err := getOrder(rune(format[0])); err == nil {
+			order = ord
+			pos++
+		}
 	}
 
-	// Extract the groups (matches[2])
-	// Find all individual groups
-	individualMatches := groupRegexp.FindAllStringSubmatch(matches[2], -1)
-
-	// Print each group with parsed number and char
-	for _, match := range individualMatches {
-		var number int
+	for pos < len(format) {
+		c := format[pos]
+		if isSpace(c) {
+			pos++
+			continue
+		}
 
-		numberStr := match[1]
-		formatRune := cFormatRune(rune(match[2][0]))
+		number := 1
+		if isDigit(c) {
+			start := pos
+			number = 0
+			for ; pos < len(format) && isDigit(format[pos]); pos++ {
+				digit := int(format[pos] - '0')
+				if number > (math.MaxInt-digit)/10 {
+					return nil, nil, &FormatError{format, start, "total struct size too long"}
+				}
+				number = number*10 + digit
+			}
+			if pos == len(format) {
+				return nil, nil, &FormatError{format, start, "repeat count given without format specifier"}
+			}
+			c = format[pos]
+		}
 
-		if numberStr == "" {
-			number = 1
-		} else {
-			number, _ = strconv.Atoi(numberStr) // check on err not needed cause of match `\d` regexp
+		formatRune := cFormatRune(c)
+		switch formatRune {
+		case tInt128, tUInt128:
+			if number != 1 {
+				return nil, nil, &FormatError{format, pos, fmt.Sprintf("repeat count given for '%c', repeat the character instead", c)}
+			}
+			group := newFormatGroup(16, tString)
+			group.integer = formatRune
+			formatGroups = append(formatGroups, group)
+			pos++
+			continue
+		case tIntN, tUIntN:
+			if number == 0 {
+				return nil, nil, &FormatError{format, pos, fmt.Sprintf("'%c' integer of 0 bytes", c)}
+			}
+			group := newFormatGroup(number, tString)
+			group.integer = formatRune
+			formatGroups = append(formatGroups, group)
+			pos++
+			continue
+		}
+		if _, ok := cFormatMap[formatRune]; !ok {
+			r, _ := utf8.DecodeRuneInString(format[pos:])
+			return nil, nil, &FormatError{format, pos, fmt.Sprintf("bad char ('%c')", r)}
 		}
 		formatGroups = append(formatGroups, newFormatGroup(number, formatRune))
+		pos++
 	}
 	return order, formatGroups, nil
 }
@@ -90,17 +131,54 @@ func parseFormatAndCalcSize(format string) (binary.ByteOrder, []formatGroup, int
 	if err != nil {
 		return nil, nil, -1, -1, err
 	}
+	orderChar := getOrderChar(format)
+	for i := range groups {
+		groups[i].order = order
+		groups[i].orderChar = orderChar
+	}
+	buffer_size, items_num, err := layoutGroups(groups, nil)
+	if err != nil {
+		return nil, nil, -1, -1, err
+	}
+	return order, groups, buffer_size, items_num, nil
+}
+
+// layoutGroups computes the padding and offset of the groups and returns the struct size and number of items,
+// the groups of native alignment are aligned like the members of a C struct, as well as the nested structs
+func layoutGroups(groups []formatGroup, nested []nestedStruct) (int, int, error) {
 	buffer_size := 0
 	items_num := 0
-	for _, group := range groups {
+	end := 0
+	for i := range groups {
+		group := &groups[i]
+		align := groupAlignment(*group)
+		for _, n := range nested {
+			if n.first == i && n.alignment > align {
+				align = n.alignment
+			}
+		}
+		buffer_size += (align - buffer_size%align) % align
+		group.padding = buffer_size - end
+		group.offset = buffer_size
+		group.first = items_num
+		if group.alignment > 0 && group.number > (math.MaxInt-buffer_size)/group.alignment {
+			return -1, -1, fmt.Errorf("struct.error: total struct size too long")
+		}
 		buffer_size += group.number * group.alignment
+		end = buffer_size
 		if group.format == tString {
 			items_num++
 		} else {
 			items_num += group.number
 		}
+		// a nested struct is padded to a multiple of its alignment, like sizeof of a C struct
+		for _, n := range nested {
+			if n.last == i {
+				buffer_size += (n.alignment - buffer_size%n.alignment) % n.alignment
+			}
+		}
 	}
-	return order, groups, buffer_size, items_num, nil
+	return buffer_size, items_num, nil
 }
 
 // NewStruct(fmt) --> compiled PyStruct object
@@ -112,6 +190,15 @@ type PyStruct struct {
 	size      int
 	items_num int
 	groups    []formatGroup
+	names     []string   // optional field names, one per format group
+	table     *itemTable // items in the packing order, built on first use, see items()
+	nested    []nestedStruct
+	checksums []checksumField
+	constants []constantField
+	enums     []enumField
+	scales    []scaledField
+	bigints   []bigIntField
+	omitConst bool // Unpack omits the constant values
 }
 
 // NewStruct(fmt) --> compiled pyStruct object
@@ -120,13 +207,43 @@ func NewStruct(format string) (PyStruct, error) {
 	if err != nil {
 		return PyStruct{}, err
 	}
-	return PyStruct{
+	s := PyStruct{
 		format:    format,
 		size:      size,
 		order:     order,
 		groups:    groups,
 		items_num: items_num,
-	}, nil
+		table:     &itemTable{},
+	}
+	for _, group := range groups {
+		if group.integer != 0 {
+			if s, err = s.withBigInt(group.first, group.integer == tInt128 || group.integer == tIntN, group.integer == tUInt128); err != nil {
+				return PyStruct{}, err
+			}
+		}
+	}
+	return s, nil
+}
+
+// NewStructWithNames(fmt, names...) --> compiled PyStruct object with named fields.
+// A field is a format group like "3s" or "2h", so names must have one entry per group.
+func NewStructWithNames(format string, names ...string) (PyStruct, error) {
+	s, err := NewStruct(format)
+	if err != nil {
+		return PyStruct{}, err
+	}
+	if len(names) != len(s.groups) {
+		return PyStruct{}, fmt.Errorf("struct.error: format has %d fields, got %d names", len(s.groups), len(names))
+	}
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name != "" && seen[name] {
+			return PyStruct{}, fmt.Errorf("struct.error: duplicate field name %q", name)
+		}
+		seen[name] = true
+	}
+	s.names = append([]string(nil), names...)
+	return s, nil
 }
 
 func (s *PyStruct) Format() string {
@@ -137,41 +254,182 @@ func (s *PyStruct) Size() int {
 	return s.size
 }
 
+// Names returns the field names, nil if the struct has no named fields
+func (s *PyStruct) Names() []string {
+	if s.names == nil {
+		return nil
+	}
+	return append([]string(nil), s.names...)
+}
+
+// namedItem returns the index of the value of the named field, like in the result of Fields,
+// fields with a repeat count like "3h" hold several values and are rejected
+func (s *PyStruct) namedItem(name string) (int, error) {
+	if s.names == nil {
+		return 0, fmt.Errorf("struct.error: struct has no named fields")
+	}
+	for g, group := range s.groups {
+		if name == "" || s.fieldName(g) != name {
+			continue
+		}
+		if group.format != tString && group.number != 1 {
+			return 0, fmt.Errorf("struct.error: field %q holds %d values", name, group.number)
+		}
+		return group.first, nil
+	}
+	return 0, fmt.Errorf("struct.error: struct has no field %q", name)
+}
+
+// fieldName returns the name of the i-th field, or an empty string
+func (s *PyStruct) fieldName(i int) string {
+	if i < len(s.names) {
+		return s.names[i]
+	}
+	return ""
+}
+
 // Return a bytes object containing the values v1, v2, … packed according to the format string format.
 // The arguments must match the values required by the format exactly.
 func (s *PyStruct) Pack(intf ...interface{}) ([]byte, error) {
-	var buffer []byte
+	buffer, err := s.AppendPack(make([]byte, 0, s.size), intf...)
+	if err != nil {
+		return nil, err
+	}
+	return buffer, nil
+}
 
+// AppendPack appends the values v1, v2, … packed according to the format string format
+// to dst and returns the extended buffer, in the style of strconv.Append* functions.
+// The packed bytes are written directly into the spare capacity of dst if there is enough of it.
+// On error dst is returned truncated to its original length.
+func (s *PyStruct) AppendPack(dst []byte, intf ...interface{}) ([]byte, error) {
+	if len(s.checksums) > 0 || len(s.constants) > 0 {
+		intf = s.packValues(intf)
+	}
 	if s.items_num != len(intf) {
-		return nil, fmt.Errorf("struct.error: format requires %d items, got %d", s.items_num, len(intf))
+		return dst, fmt.Errorf("struct.error: format requires %d items, got %d", s.items_num, len(intf))
+	}
+	if len(s.enums) > 0 || len(s.scales) > 0 || len(s.bigints) > 0 {
+		intf = append([]interface{}(nil), intf...)
+		for _, e := range s.enums {
+			if !e.known(intf[e.item]) {
+				return dst, &EnumError{e.item, intf[e.item]}
+			}
+			intf[e.item] = e.rawValue(intf[e.item])
+		}
+		for _, sc := range s.scales {
+			value, err := sc.rawValue(intf[sc.item])
+			if err != nil {
+				return dst, err
+			}
+			intf[sc.item] = value
+		}
+		for _, f := range s.bigints {
+			value, err := f.rawValue(intf[f.item])
+			if err != nil {
+				return dst, err
+			}
+			intf[f.item] = value
+		}
 	}
 
-	for i, group := range s.groups {
+	start := len(dst)
+	if cap(dst)-start < s.size {
+		dst = append(dst, make([]byte, s.size)...)[:start]
+	}
+
+	index := 0
+	for _, group := range s.groups {
+		for i := 0; i < group.padding; i++ {
+			dst = append(dst, 0)
+		}
 		if group.format == tString {
-			switch value := intf[i].(type) {
-			case string:
-				buffer = append(buffer, buildString(value)...)
-			default:
-				return nil, fmt.Errorf("struct.error: argument for 's' must be a bytes object")
+			value, ok := intf[index].(string)
+			if !ok {
+				return dst[:start], fmt.Errorf("struct.error: argument for 's' must be a bytes object")
 			}
+			dst = appendString(dst, value, group.number)
+			index++
 		} else {
 			for num := 0; num < group.number; num++ {
-				if data := buildValue(intf[i], group.format, s.order); data != nil {
-					buffer = append(buffer, data...)
-				} else {
-					return nil, fmt.Errorf("struct.error: required argument is not an %s", cFormatStringMap[group.format])
+				var ok bool
+				if dst, ok = appendValue(dst, intf[index], group.format, group.order); !ok {
+					return dst[:start], fmt.Errorf("struct.error: required argument is not an %s", cFormatStringMap[group.format])
 				}
+				index++
 			}
 		}
 	}
+	// trailing pad bytes of a nested struct
+	for len(dst)-start < s.size {
+		dst = append(dst, 0)
+	}
+	s.fillChecksums(dst[start:])
 
-	return buffer, nil
+	return dst, nil
+}
+
+// packValues returns the values of all items with the constants and zero checksums,
+// the values of all items or of the other items only are accepted
+func (s *PyStruct) packValues(intf []interface{}) []interface{} {
+	full := len(intf) == s.items_num
+	if !full && len(intf) != s.items_num-len(s.checksums)-len(s.constants) {
+		return intf
+	}
+	values := make([]interface{}, 0, s.items_num)
+	next := 0
+	for i := 0; i < s.items_num; i++ {
+		c, isConstant := s.constant(i)
+		switch {
+		case isConstant:
+			values = append(values, c.value)
+		case s.isChecksum(i):
+			values = append(values, reflect.Zero(goTypeMap[s.item(i).format]).Interface())
+		default:
+			values = append(values, intf[next])
+			next++
+			continue
+		}
+		// the given values of constants and checksums are ignored
+		if full {
+			next++
+		}
+	}
+	return values
 }
 
 // Pack the values v1, v2, … according to the format string format
 // and write the packed bytes into the writable buffer
 // starting at position offset. Note that offset is a required argument.
-func (s *PyStruct) PackInto(buffer []byte, offset int, intf ...interface{}) ([]byte, error) {
+// The buffer is never reallocated: if it has no space for the packed bytes an error is returned.
+// A negative offset counts from the end of the buffer.
+func (s *PyStruct) PackInto(buffer []byte, offset int, intf ...interface{}) error {
+	if offset < 0 {
+		if offset+s.size > 0 {
+			return fmt.Errorf("struct.error: no space to pack %d bytes at offset %d", s.size, offset)
+		}
+		if offset+len(buffer) < 0 {
+			return fmt.Errorf("struct.error: offset %d out of range for %d-byte buffer", offset, len(buffer))
+		}
+		offset += len(buffer)
+	}
+
+	if len(buffer)-offset < s.size {
+		return fmt.Errorf(
+			"struct.error: pack_into requires a buffer of at least %d bytes for packing %d bytes at offset %d (actual buffer size is %d)",
+			s.size+offset, s.size, offset, len(buffer),
+		)
+	}
+
+	// buffer has enough space after offset, so AppendPack never reallocates it
+	_, err := s.AppendPack(buffer[offset:offset], intf...)
+	return err
+}
+
+// PackIntoGrow works like PackInto, but grows the buffer when it is too small to hold
+// the packed bytes at offset. The result may be a newly allocated slice,
+// so it has to be used instead of the passed buffer.
+func (s *PyStruct) PackIntoGrow(buffer []byte, offset int, intf ...interface{}) ([]byte, error) {
 	partBuf, err := s.Pack(intf...)
 	if err != nil {
 		return nil, err
@@ -198,38 +456,185 @@ func (s *PyStruct) PackInto(buffer []byte, offset int, intf ...interface{}) ([]b
 // The result is an []interface{} even if it contains exactly one item.
 // The buffer’s size in bytes, starting at position offset,
 // must be at least the size required by the format, as reflected by CalcSize().
+// A negative offset counts from the end of the buffer.
 func (s *PyStruct) UnpackFrom(buffer []byte, offset int) ([]interface{}, error) {
-	var parsedValues []interface{}
+	values, _, err := s.UnpackFromN(buffer, offset)
+	return values, err
+}
 
-	if len(buffer)-offset != s.size {
+// UnpackFromN works like UnpackFrom and additionally returns the number of bytes consumed,
+// so that a buffer of concatenated structures can be walked by advancing offset by n.
+func (s *PyStruct) UnpackFromN(buffer []byte, offset int) ([]interface{}, int, error) {
+	if offset < 0 {
+		if offset+len(buffer) < 0 {
+			return nil, 0, fmt.Errorf("struct.error: offset %d out of range for %d-byte buffer", offset, len(buffer))
+		}
+		offset += len(buffer)
+	}
+
+	if len(buffer)-offset < s.size {
+		return nil, 0, fmt.Errorf(
+			"struct.error: unpack_from requires a buffer of at least %d bytes for unpacking %d bytes at offset %d (actual buffer size is %d)",
+			s.size+offset, s.size, offset, len(buffer),
+		)
+	}
+
+	if err := s.verify(buffer[offset : offset+s.size]); err != nil {
+		return nil, 0, err
+	}
+	return s.unpackValues(buffer[offset : offset+s.size]), s.size, nil
+}
+
+// Unpack from the buffer buffer (presumably packed by Pack(format, ...))
+// according to the format string format. The result is an []interface{} even if it contains exactly one item.
+// The buffer’s size in bytes must match the size required by the format, as reflected by CalcSize().
+func (s *PyStruct) Unpack(buffer []byte) ([]interface{}, error) {
+	if len(buffer) != s.size {
 		return nil, fmt.Errorf("struct.error: unpack requires a buffer of %d bytes", s.size)
 	}
+	if err := s.verify(buffer); err != nil {
+		return nil, err
+	}
+	return s.unpackValues(buffer), nil
+}
+
+// unpack decodes the values from buffer, which must be exactly s.size bytes long
+func (s *PyStruct) unpack(buffer []byte) []interface{} {
+	parsedValues := make([]interface{}, 0, s.items_num)
+	offset := 0
 
 	for _, group := range s.groups {
+		offset += group.padding
 		if group.format == tString {
 			bytesShift := group.alignment * group.number
 			value := parseString(buffer[offset : offset+bytesShift])
 			offset += bytesShift
-			// fmt.Printf("Fmt: %c, %v, shift->%d\n", group.format, value, bytesShift)
 			parsedValues = append(parsedValues, value)
 		} else {
 			bytesShift := group.alignment
 			for num := 0; num < group.number; num++ {
-				value := parseValue(buffer[offset:offset+bytesShift], group.format, s.order)
+				value := parseValue(buffer[offset:offset+bytesShift], group.format, group.order)
 				offset += bytesShift
-				// fmt.Printf("Fmt: %c, %v, shift->%d\n", group.format, value, bytesShift)
 				parsedValues = append(parsedValues, value)
 			}
 		}
 	}
-	return parsedValues, nil
+	for _, e := range s.enums {
+		parsedValues[e.item] = e.convert(parsedValues[e.item])
+	}
+	for _, sc := range s.scales {
+		parsedValues[sc.item] = sc.convert(parsedValues[sc.item])
+	}
+	for _, f := range s.bigints {
+		parsedValues[f.item] = f.convert(parsedValues[f.item])
+	}
+	return parsedValues
 }
 
-// Unpack from the buffer buffer (presumably packed by Pack(format, ...))
-// according to the format string format. The result is an []interface{} even if it contains exactly one item.
-// The buffer’s size in bytes must match the size required by the format, as reflected by CalcSize().
-func (s *PyStruct) Unpack(buffer []byte) ([]interface{}, error) {
-	return s.UnpackFrom(buffer, 0)
+// item is a single value of the struct, a whole group for 's'
+type item struct {
+	group  int // index of the format group
+	index  int // repeat index in the format group
+	offset int // byte offset in the packed struct
+	size   int // size in bytes
+	format cFormatRune
+	seq    int // index of the item in the packing order
+}
+
+// itemTable holds the items of a struct, shared by its copies
+type itemTable struct {
+	once  sync.Once
+	items []item
+}
+
+// items returns the values of the struct in the packing order, it takes memory proportional to the number of items:
+// use item to access a single value. The returned slice is shared and must not be modified.
+func (s *PyStruct) items() []item {
+	if s.table == nil {
+		return s.buildItems()
+	}
+	s.table.once.Do(func() { s.table.items = s.buildItems() })
+	return s.table.items
+}
+
+// item returns the i-th value of the struct in the packing order, 0 <= i < items_num,
+// in a time independent of the repeat counts
+func (s *PyStruct) item(i int) item {
+	g := sort.Search(len(s.groups), func(g int) bool { return s.groups[g].first > i }) - 1
+	group := s.groups[g]
+	if group.format == tString {
+		return item{g, 0, group.offset, group.number * group.alignment, group.format, i}
+	}
+	num := i - group.first
+	return item{g, num, group.offset + num*group.alignment, group.alignment, group.format, i}
+}
+
+func (s *PyStruct) buildItems() []item {
+	items := make([]item, 0, s.items_num)
+	for i, group := range s.groups {
+		if group.format == tString {
+			items = append(items, item{i, 0, group.offset, group.number * group.alignment, group.format, len(items)})
+			continue
+		}
+		for num := 0; num < group.number; num++ {
+			items = append(items, item{i, num, group.offset + num*group.alignment, group.alignment, group.format, len(items)})
+		}
+	}
+	return items
+}
+
+// decodeItem decodes a single value from the packed struct buffer
+func (s *PyStruct) decodeItem(buffer []byte, it item) interface{} {
+	data := buffer[it.offset : it.offset+it.size]
+	if it.format == tString {
+		if f, ok := s.bigInt(it.seq); ok {
+			return f.convert(parseString(data))
+		}
+		return parseString(data)
+	}
+	value := parseValue(data, it.format, s.groups[it.group].order)
+	if e, ok := s.enum(it.seq); ok {
+		return e.convert(value)
+	}
+	if sc, ok := s.scaled(it.seq); ok {
+		return sc.convert(value)
+	}
+	return value
+}
+
+// encodeItem packs the value of the item into the buffer in place
+func (s *PyStruct) encodeItem(buffer []byte, it item, value interface{}) error {
+	if e, ok := s.enum(it.seq); ok {
+		if !e.known(value) {
+			return &EnumError{it.seq, value}
+		}
+		value = e.rawValue(value)
+	}
+	if sc, ok := s.scaled(it.seq); ok {
+		var err error
+		if value, err = sc.rawValue(value); err != nil {
+			return err
+		}
+	}
+	if f, ok := s.bigInt(it.seq); ok {
+		var err error
+		if value, err = f.rawValue(value); err != nil {
+			return err
+		}
+	}
+	data := buffer[it.offset : it.offset : it.offset+it.size]
+	if it.format == tString {
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("struct.error: argument for 's' must be a bytes object")
+		}
+		appendString(data, str, it.size)
+		return nil
+	}
+	if _, ok := appendValue(data, value, it.format, s.groups[it.group].order); !ok {
+		return fmt.Errorf("struct.error: required argument is not an %s", cFormatStringMap[it.format])
+	}
+	return nil
 }
 
 // Iteratively unpack from the buffer buffer according to the format string format.
@@ -237,33 +642,30 @@ func (s *PyStruct) Unpack(buffer []byte) ([]interface{}, error) {
 // The buffer’s size in bytes must be a multiple of the size required by the format, as reflected by CalcSize()
 func (s *PyStruct) IterUnpack(buffer []byte) (<-chan interface{}, <-chan error) {
 	parsedValues := make(chan interface{})
-	errors := make(chan error)
+	// buffered, so an error doesn't block while the caller is still reading values
+	errors := make(chan error, 1)
 
 	go func() {
 		defer close(parsedValues)
 		defer close(errors)
 
-		offset := 0
+		if s.size == 0 {
+			errors <- fmt.Errorf("struct.error: cannot iteratively unpack with a struct of length 0")
+			return
+		}
 
-		if len(buffer)-offset != s.size {
-			errors <- fmt.Errorf("struct.error: unpack requires a buffer of %d bytes", s.size)
+		if len(buffer)%s.size != 0 {
+			errors <- fmt.Errorf("struct.error: iterative unpacking requires a buffer of a multiple of %d bytes", s.size)
+			return
 		}
 
-		for _, group := range s.groups {
-			if group.format == tString {
-				bytesShift := group.alignment * group.number
-				value := parseString(buffer[offset : offset+bytesShift])
-				offset += bytesShift
-				// fmt.Printf("Fmt: %c, %v, shift->%d\n", group.format, value, bytesShift)
+		for offset := 0; offset < len(buffer); offset += s.size {
+			if err := s.verify(buffer[offset : offset+s.size]); err != nil {
+				errors <- err
+				return
+			}
+			for _, value := range s.unpackValues(buffer[offset : offset+s.size]) {
 				parsedValues <- value
-			} else {
-				bytesShift := group.alignment
-				for num := 0; num < group.number; num++ {
-					value := parseValue(buffer[offset:offset+bytesShift], group.format, s.order)
-					offset += bytesShift
-					// fmt.Printf("Fmt: %c, %v, shift->%d\n", group.format, value, bytesShift)
-					parsedValues <- value
-				}
 			}
 		}
 	}()
@@ -275,51 +677,85 @@ func (s *PyStruct) IterUnpack(buffer []byte) (<-chan interface{}, <-chan error)
 // (and hence of the bytes object produced by pack(format, ...))
 // corresponding to the format string format
 func CalcSize(format string) (int, error) {
-	_, _, size, _, err := parseFormatAndCalcSize(format)
+	s, err := compile(format)
 	if err != nil {
 		return -1, err
 	}
-	return size, nil
+	return s.size, nil
 }
 
 // Return a bytes object containing the values v1, v2, … packed according to the format string format.
 // The arguments must match the values required by the format exactly.
 func Pack(format string, intf ...interface{}) ([]byte, error) {
-	s, err := NewStruct(format)
+	s, err := compile(format)
 	if err != nil {
 		return nil, err
 	}
 	return s.Pack(intf...)
 }
 
+// AppendPack appends the values v1, v2, … packed according to the format string format
+// to dst and returns the extended buffer, in the style of strconv.Append* functions.
+// On error dst is returned truncated to its original length.
+func AppendPack(format string, dst []byte, intf ...interface{}) ([]byte, error) {
+	s, err := compile(format)
+	if err != nil {
+		return dst, err
+	}
+	return s.AppendPack(dst, intf...)
+}
+
 // Pack the values v1, v2, … according to the format string format
 // and write the packed bytes into the writable buffer
 // starting at position offset. Note that offset is a required argument.
-func PackInto(format string, buffer []byte, offset int, intf ...interface{}) ([]byte, error) {
-	s, err := NewStruct(format)
+// The buffer is never reallocated: if it has no space for the packed bytes an error is returned.
+// A negative offset counts from the end of the buffer.
+func PackInto(format string, buffer []byte, offset int, intf ...interface{}) error {
+	s, err := compile(format)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	return s.PackInto(buffer, offset, intf...)
 }
 
+// PackIntoGrow works like PackInto, but grows the buffer when it is too small to hold
+// the packed bytes at offset. The result may be a newly allocated slice,
+// so it has to be used instead of the passed buffer.
+func PackIntoGrow(format string, buffer []byte, offset int, intf ...interface{}) ([]byte, error) {
+	s, err := compile(format)
+	if err != nil {
+		return nil, err
+	}
+	return s.PackIntoGrow(buffer, offset, intf...)
+}
+
 // Unpack from buffer starting at position offset, according to the format string format.
 // The result is an []interface{} even if it contains exactly one item.
 // The buffer’s size in bytes, starting at position offset,
 // must be at least the size required by the format, as reflected by CalcSize().
+// A negative offset counts from the end of the buffer.
 func UnpackFrom(format string, buffer []byte, offset int) ([]interface{}, error) {
-	s, err := NewStruct(format)
+	s, err := compile(format)
 	if err != nil {
 		return nil, err
 	}
 	return s.UnpackFrom(buffer, offset)
 }
 
+// UnpackFromN works like UnpackFrom and additionally returns the number of bytes consumed.
+func UnpackFromN(format string, buffer []byte, offset int) ([]interface{}, int, error) {
+	s, err := compile(format)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.UnpackFromN(buffer, offset)
+}
+
 // Unpack from the buffer buffer (presumably packed by Pack(format, ...))
 // according to the format string format. The result is an []interface{} even if it contains exactly one item.
 // The buffer’s size in bytes must match the size required by the format, as reflected by CalcSize().
 func Unpack(format string, buffer []byte) ([]interface{}, error) {
-	s, err := NewStruct(format)
+	s, err := compile(format)
 	if err != nil {
 		return nil, err
 	}
@@ -331,13 +767,14 @@ func Unpack(format string, buffer []byte) ([]interface{}, error) {
 // The buffer’s size in bytes must be a multiple of the size required by the format, as reflected by CalcSize()
 func IterUnpack(format string, buffer []byte) (<-chan interface{}, <-chan error) {
 	parsedValues := make(chan interface{})
-	errors := make(chan error)
+	// buffered, so an error doesn't block while the caller is still reading values
+	errors := make(chan error, 1)
 
 	go func() {
 		defer close(parsedValues)
 		defer close(errors)
 
-		s, err := NewStruct(format)
+		s, err := compile(format)
 		if err != nil {
 			errors <- err
 			return
@@ -2,6 +2,12 @@ package pystruct
 
 import (
 	"bytes"
+	"errors"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
 	"unicode"
 )
@@ -189,6 +195,37 @@ func TestCalcSize(t *testing.T) {
 	}
 }
 
+// Compiling a format takes memory proportional to its groups, not to its repeat counts
+func TestCalcSizeHugeRepeatCount(t *testing.T) {
+	if strconv.IntSize < 64 {
+		t.Skip("the size doesn't fit int")
+	}
+	size, err := CalcSize("<100000000000B")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 100000000000 {
+		t.Errorf("Size: Expected: %d\nActual: %d\n", 100000000000, size)
+	}
+
+	s, err := NewStruct("<H100000000000Bi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset, _ := s.OffsetOf(100000000001); offset != 100000000002 {
+		t.Errorf("Offset: Expected: %d\nActual: %d\n", 100000000002, offset)
+	}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, err := NewStruct("1000000B"); err != nil {
+		t.Fatal(err)
+	}
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<16 {
+		t.Errorf("NewStruct allocated %d bytes", allocated)
+	}
+}
+
 func TestPack(t *testing.T) {
 	// intf := []interface{}{"abc", 1.01, 3}
 	intf := []interface{}{"abc", 1.01}
@@ -206,9 +243,52 @@ func TestPack(t *testing.T) {
 }
 
 func TestPackInto(t *testing.T) {
+	intf := []interface{}{"abc", 1.01}
+	buffer := []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	err := PackInto("<3sf", buffer, 2, intf...)
+
+	expected := []byte{0xff, 0xff, 97, 98, 99, 174, 71, 129, 63, 0xff}
+
+	if err != nil {
+		t.Error("Unbound error:", err)
+	}
+
+	if !bytes.Equal(buffer, expected) {
+		t.Errorf("Expected: %v\nActual: %v\n", expected, buffer)
+	}
+
+	buffer = []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	err = PackInto("<3sf", buffer, -7, intf...)
+	expected = []byte{0xff, 0xff, 0xff, 97, 98, 99, 174, 71, 129, 63}
+
+	if err != nil {
+		t.Error("Unbound error:", err)
+	}
+
+	if !bytes.Equal(buffer, expected) {
+		t.Errorf("Expected: %v\nActual: %v\n", expected, buffer)
+	}
+}
+
+func TestPackIntoNoSpace(t *testing.T) {
+	intf := []interface{}{"abc", 1.01}
+	buffer := []byte{0xff, 0xff, 0xff, 0xff}
+
+	for _, offset := range []int{0, 2, -2, -12} {
+		if err := PackInto("<3sf", buffer, offset, intf...); err == nil {
+			t.Errorf("expected error for offset %d", offset)
+		}
+	}
+
+	if !bytes.Equal(buffer, []byte{0xff, 0xff, 0xff, 0xff}) {
+		t.Errorf("buffer was modified: %v", buffer)
+	}
+}
+
+func TestPackIntoGrow(t *testing.T) {
 	intf := []interface{}{"abc", 1.01}
 	buffer := []byte{0xff, 0xff, 0xff, 0xff}
-	byteArray, err := PackInto("<3sf", buffer, 2, intf...)
+	byteArray, err := PackIntoGrow("<3sf", buffer, 2, intf...)
 
 	expected := []byte{0xff, 0xff, 97, 98, 99, 174, 71, 129, 63}
 
@@ -219,7 +299,6 @@ func TestPackInto(t *testing.T) {
 	if !bytes.Equal(byteArray, expected) {
 		t.Errorf("Expected: %v\nActual: %v\n", expected, byteArray)
 	}
-
 }
 
 func TestUnpack(t *testing.T) {
@@ -327,3 +406,242 @@ func TestIterUnpack(t *testing.T) {
 		t.Error("Unbound error:", err)
 	}
 }
+
+func TestIterUnpackRecords(t *testing.T) {
+	iterator, errs := IterUnpack("<Bh", []byte{1, 2, 0, 3, 0xfc, 0xff})
+	var values []interface{}
+	for value := range iterator {
+		values = append(values, value)
+	}
+	for err := range errs {
+		t.Error("Unbound error:", err)
+	}
+	if expected := []interface{}{uint8(1), int16(2), uint8(3), int16(-4)}; !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected: %v\nActual: %v\n", expected, values)
+	}
+
+	for format, buffer := range map[string][]byte{"<Bh": {1, 2, 0, 3}, "": {1}} {
+		iterator, errs := IterUnpack(format, buffer)
+		for range iterator {
+			t.Errorf("%q: Unexpected value for a buffer of %d bytes", format, len(buffer))
+		}
+		if err := <-errs; err == nil {
+			t.Errorf("%q: Expected error for a buffer of %d bytes", format, len(buffer))
+		}
+	}
+}
+
+func TestUnpackFromTrailingBytes(t *testing.T) {
+	byteArray := []byte{0, 97, 98, 99, 1, 0, 0xff, 0xff}
+	intf, err := UnpackFrom("<3sh", byteArray, 1)
+	if err != nil {
+		t.Fatal("Unbound error:", err)
+	}
+	if intf[0] != "abc" || intf[1] != int16(1) {
+		t.Errorf("wrong values: %v", intf)
+	}
+}
+
+func TestUnpackFromOffsets(t *testing.T) {
+	byteArray := []byte{97, 98, 99}
+
+	intf, err := UnpackFrom("b", byteArray, -1)
+	if err != nil {
+		t.Fatal("Unbound error:", err)
+	}
+	if intf[0] != int8(99) {
+		t.Errorf("wrong value for negative offset: %v", intf[0])
+	}
+
+	for _, offset := range []int{2, 5, -5} {
+		if _, err := UnpackFrom("<h", byteArray, offset); err == nil {
+			t.Errorf("expected error for offset %d", offset)
+		}
+	}
+
+	if _, err := Unpack("<h", byteArray); err == nil {
+		t.Error("expected error for Unpack with trailing bytes")
+	}
+}
+
+func TestUnpackFromN(t *testing.T) {
+	s, err := NewStruct("<bh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	byteArray := []byte{1, 2, 0, 3, 4, 0, 0xff}
+
+	var records [][]interface{}
+	for offset := 0; len(byteArray)-offset >= s.Size(); {
+		intf, n, err := s.UnpackFromN(byteArray, offset)
+		if err != nil {
+			t.Fatal("Unbound error:", err)
+		}
+		records = append(records, intf)
+		offset += n
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	if records[1][0] != int8(3) || records[1][1] != int16(4) {
+		t.Errorf("wrong second record: %v", records[1])
+	}
+}
+
+func TestAppendPack(t *testing.T) {
+	header, err := NewStruct("<BH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := NewStruct("<2h3s")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := make([]byte, 0, 64)
+	buffer, err := header.AppendPack(pool, uint8(1), uint16(7))
+	if err != nil {
+		t.Fatal("Unbound error:", err)
+	}
+	buffer, err = body.AppendPack(buffer, int16(-1), int16(2), "ab")
+	if err != nil {
+		t.Fatal("Unbound error:", err)
+	}
+
+	expected := []byte{1, 7, 0, 0xff, 0xff, 2, 0, 97, 98, 0}
+	if !bytes.Equal(buffer, expected) {
+		t.Errorf("Expected: %v\nActual: %v\n", expected, buffer)
+	}
+	if &buffer[0] != &pool[:1][0] {
+		t.Error("buffer was reallocated")
+	}
+
+	buffer, err = body.AppendPack(buffer, int16(1), "wrong", "ab")
+	if err == nil {
+		t.Error("expected error for wrong argument type")
+	}
+	if !bytes.Equal(buffer, expected) {
+		t.Errorf("buffer was not truncated on error: %v", buffer)
+	}
+}
+
+func TestParseFormatWhitespace(t *testing.T) {
+	for _, format := range []string{"< h", "<h ", "<\th\n", "h\vh", "", "<"} {
+		if _, err := CalcSize(format); err != nil {
+			t.Errorf("unexpected error for %q: %s", format, err)
+		}
+	}
+	for _, format := range []string{" <h", "\t<h", "2 h", "h<h"} {
+		if _, err := CalcSize(format); err == nil {
+			t.Errorf("expected error for %q", format)
+		}
+	}
+}
+
+func TestParseFormatErrors(t *testing.T) {
+	cases := []struct {
+		format string
+		pos    int
+	}{
+		{"<3z", 2},
+		{"<hh2", 3},
+		{"3", 0},
+		{"<10s 2 b", 6},
+		{"99999999999999999999h", 0},
+		{"<hЖ", 2},
+	}
+
+	for _, c := range cases {
+		_, err := NewStruct(c.format)
+		var formatErr *FormatError
+		if !errors.As(err, &formatErr) {
+			t.Errorf("expected FormatError for %q, got %v", c.format, err)
+			continue
+		}
+		if formatErr.Pos != c.pos {
+			t.Errorf("wrong error position for %q: expected %d, got %d (%s)", c.format, c.pos, formatErr.Pos, err)
+		}
+	}
+
+	if _, err := NewStruct("<9223372036854775807q"); err == nil {
+		t.Error("expected error for struct size overflow")
+	}
+}
+
+// parseFormatRegexp is the former regexp based parser kept as a reference for FuzzParseFormat
+func parseFormatRegexp(format string) (string, []formatGroup, bool) {
+	formatRegexp := regexp.MustCompile(`^([@<>=!])?((\d*[cbBhHiIqQlLfds])+)$`)
+	groupRegexp := regexp.MustCompile(`(\d*)([cbBhHiIqQlLfds])`)
+
+	matches := formatRegexp.FindStringSubmatch(strings.ReplaceAll(format, " ", ""))
+	if len(matches) == 0 {
+		return "", nil, false
+	}
+
+	var groups []formatGroup
+	for _, match := range groupRegexp.FindAllStringSubmatch(matches[2], -1) {
+		number := 1
+		if match[1] != "" {
+			number, _ = strconv.Atoi(match[1])
+		}
+		groups = append(groups, newFormatGroup(number, cFormatRune(match[2][0])))
+	}
+	return matches[1], groups, true
+}
+
+func FuzzParseFormat(f *testing.F) {
+	for _, format := range []string{"<3sf", "<10s2bd", "3sf", "3<sf", "<10s 2b d", ">HhIiQq", "!0s0h", "=?c", "@ld", "<3z"} {
+		f.Add(format)
+	}
+
+	f.Fuzz(func(t *testing.T, format string) {
+		order, groups, err := parseFormat(format)
+
+		// the old parser stripped only spaces and had no '?' nor empty format support,
+		// so compare only formats both parsers are supposed to agree on
+		if strings.ContainsAny(format, " \t\n\r\v\f?") {
+			return
+		}
+		prefix, oldGroups, ok := parseFormatRegexp(format)
+
+		var formatErr *FormatError
+		switch {
+		case ok && err != nil:
+			if errors.As(err, &formatErr) && formatErr.Msg == "total struct size too long" {
+				return
+			}
+			t.Fatalf("%q: rejected by the new parser: %s", format, err)
+		case !ok && err == nil && len(groups) > 0:
+			t.Fatalf("%q: accepted by the new parser only", format)
+		case !ok:
+			return
+		}
+
+		if !reflect.DeepEqual(groups, oldGroups) {
+			t.Fatalf("%q: groups differ: %v != %v", format, groups, oldGroups)
+		}
+		if prefix != "" {
+			if expected, _ := getOrder(rune(prefix[0])); expected != order {
+				t.Fatalf("%q: byte order differs", format)
+			}
+		}
+	})
+}
+
+func TestNewStructWithNames(t *testing.T) {
+	s, err := NewStructWithNames("<4sB3h", "magic", "version", "readings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s.Names(), []string{"magic", "version", "readings"}) {
+		t.Errorf("wrong names: %v", s.Names())
+	}
+
+	if _, err := NewStructWithNames("<4sB3h", "magic", "version"); err == nil {
+		t.Error("expected error for missing name")
+	}
+	if _, err := NewStructWithNames("<bb", "value", "value"); err == nil {
+		t.Error("expected error for duplicate name")
+	}
+}
@@ -0,0 +1,171 @@
+package pystruct
+
+import (
+	"fmt"
+	"os"
+)
+
+// RecordFile provides random access to a file of fixed size records, packed one after another
+// according to a PyStruct, through a shared memory mapping of the file.
+// The file size must be a multiple of the struct size.
+// Memory mapping is supported on Linux only, OpenRecordFile fails on other platforms.
+type RecordFile struct {
+	s        PyStruct
+	file     *os.File
+	data     []byte
+	readOnly bool
+}
+
+// OpenRecordFile maps the existing file of records packed according to s,
+// the records can't be modified if readOnly is set
+func OpenRecordFile(name string, s PyStruct, readOnly bool) (*RecordFile, error) {
+	if s.size == 0 {
+		return nil, fmt.Errorf("struct.error: records of a struct of length 0 are not supported")
+	}
+
+	flag := os.O_RDWR
+	if readOnly {
+		flag = os.O_RDONLY
+	}
+	file, err := os.OpenFile(name, flag, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.Size()%int64(s.size) != 0 {
+		file.Close()
+		return nil, fmt.Errorf("struct.error: record file size %d is not a multiple of %d bytes", info.Size(), s.size)
+	}
+	if int64(int(info.Size())) != info.Size() {
+		file.Close()
+		return nil, fmt.Errorf("struct.error: record file size %d is too large to be mapped", info.Size())
+	}
+
+	var data []byte
+	if info.Size() > 0 {
+		if data, err = mmapFile(file, int(info.Size()), readOnly); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return &RecordFile{s: s, file: file, data: data, readOnly: readOnly}, nil
+}
+
+// CreateRecordFile creates or truncates the file to hold n zeroed records packed according to s
+// and maps it for reading and writing
+func CreateRecordFile(name string, s PyStruct, n int) (*RecordFile, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("struct.error: negative number of records %d", n)
+	}
+	file, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	err = file.Truncate(int64(n) * int64(s.size))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return OpenRecordFile(name, s, false)
+}
+
+// Len returns the number of records in the file
+func (f *RecordFile) Len() int {
+	return len(f.data) / f.s.size
+}
+
+// Struct returns the layout of the records
+func (f *RecordFile) Struct() PyStruct {
+	return f.s
+}
+
+func (f *RecordFile) checkIndex(i int) error {
+	if f.data == nil && f.file == nil {
+		return fmt.Errorf("struct.error: record file is closed")
+	}
+	if i < 0 || i >= f.Len() {
+		return fmt.Errorf("struct.error: record index %d out of range [0, %d)", i, f.Len())
+	}
+	return nil
+}
+
+// Record returns the mapped bytes of the i-th record, the slice is only valid until Close
+// and must not be modified if the file is opened read-only
+func (f *RecordFile) Record(i int) ([]byte, error) {
+	if err := f.checkIndex(i); err != nil {
+		return nil, err
+	}
+	offset := i * f.s.size
+	return f.data[offset : offset+f.s.size : offset+f.s.size], nil
+}
+
+// Get unpacks the i-th record
+func (f *RecordFile) Get(i int) ([]interface{}, error) {
+	record, err := f.Record(i)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.s.verify(record); err != nil {
+		return nil, err
+	}
+	return f.s.unpackValues(record), nil
+}
+
+// Set packs the values into the i-th record in place
+func (f *RecordFile) Set(i int, intf ...interface{}) error {
+	if f.readOnly {
+		return fmt.Errorf("struct.error: record file is read-only")
+	}
+	if err := f.checkIndex(i); err != nil {
+		return err
+	}
+	return f.s.PackInto(f.data, i*f.s.size, intf...)
+}
+
+// Each calls fn for the records in order until fn returns false or a record fails its checksums
+func (f *RecordFile) Each(fn func(i int, values []interface{}) bool) error {
+	if f.data == nil && f.file == nil {
+		return fmt.Errorf("struct.error: record file is closed")
+	}
+	for i := 0; i < f.Len(); i++ {
+		record := f.data[i*f.s.size : (i+1)*f.s.size]
+		if err := f.s.verify(record); err != nil {
+			return fmt.Errorf("record %d: %w", i, err)
+		}
+		if !fn(i, f.s.unpackValues(record)) {
+			break
+		}
+	}
+	return nil
+}
+
+// Sync flushes the modified records to the file
+func (f *RecordFile) Sync() error {
+	if f.readOnly || len(f.data) == 0 {
+		return nil
+	}
+	return msyncData(f.data)
+}
+
+// Close unmaps and closes the file, the modified records are written back by the system
+func (f *RecordFile) Close() error {
+	var err error
+	if f.data != nil {
+		err = munmapData(f.data)
+		f.data = nil
+	}
+	if f.file != nil {
+		if closeErr := f.file.Close(); err == nil {
+			err = closeErr
+		}
+		f.file = nil
+	}
+	return err
+}
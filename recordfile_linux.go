@@ -0,0 +1,29 @@
+//go:build linux
+
+package pystruct
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+func mmapFile(file *os.File, size int, readOnly bool) ([]byte, error) {
+	prot := syscall.PROT_READ
+	if !readOnly {
+		prot |= syscall.PROT_WRITE
+	}
+	return syscall.Mmap(int(file.Fd()), 0, size, prot, syscall.MAP_SHARED)
+}
+
+func munmapData(data []byte) error {
+	return syscall.Munmap(data)
+}
+
+func msyncData(data []byte) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_MSYNC, uintptr(unsafe.Pointer(&data[0])), uintptr(len(data)), syscall.MS_SYNC)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+
+package pystruct
+
+import (
+	"fmt"
+	"os"
+)
+
+func mmapFile(file *os.File, size int, readOnly bool) ([]byte, error) {
+	return nil, fmt.Errorf("struct.error: memory mapped record files are supported on Linux only")
+}
+
+func munmapData(data []byte) error {
+	return nil
+}
+
+func msyncData(data []byte) error {
+	return nil
+}
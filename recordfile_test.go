@@ -0,0 +1,113 @@
+//go:build linux
+
+package pystruct
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRecordFile(t *testing.T) {
+	s, _ := NewStruct("<Hi")
+	name := filepath.Join(t.TempDir(), "records.bin")
+
+	f, err := CreateRecordFile(name, s, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Len() != 3 {
+		t.Errorf("Expected 3 records, got %d", f.Len())
+	}
+	for i := 0; i < f.Len(); i++ {
+		if err := f.Set(i, uint16(i), int32(-i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Set(3, uint16(0), int32(0)); err == nil {
+		t.Error("Expected error for index out of range")
+	}
+	if err := f.Set(0, "x", int32(0)); err == nil {
+		t.Error("Expected error for a value of a wrong type")
+	}
+	if err := f.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := s.PackArray([][]interface{}{{uint16(0), int32(0)}, {uint16(1), int32(-1)}, {uint16(2), int32(-2)}})
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Expected: %v\nActual: %v\n", expected, data)
+	}
+
+	f, err = OpenRecordFile(name, s, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	values, err := f.Get(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, []interface{}{uint16(2), int32(-2)}) {
+		t.Errorf("Unexpected record %v", values)
+	}
+	if _, err := f.Get(-1); err == nil {
+		t.Error("Expected error for negative index")
+	}
+	if err := f.Set(0, uint16(0), int32(0)); err == nil {
+		t.Error("Expected error for read-only file")
+	}
+
+	var indexes []int
+	f.Each(func(i int, values []interface{}) bool {
+		indexes = append(indexes, i)
+		return i < 1
+	})
+	if !reflect.DeepEqual(indexes, []int{0, 1}) {
+		t.Errorf("Expected iteration to stop after index 1, got %v", indexes)
+	}
+}
+
+func TestRecordFileErrors(t *testing.T) {
+	s, _ := NewStruct("<Hi")
+	dir := t.TempDir()
+
+	name := filepath.Join(dir, "partial.bin")
+	if err := os.WriteFile(name, make([]byte, 7), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenRecordFile(name, s, true); err == nil {
+		t.Error("Expected error for a size not multiple of the struct size")
+	}
+
+	name = filepath.Join(dir, "empty.bin")
+	f, err := CreateRecordFile(name, s, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Len() != 0 {
+		t.Errorf("Expected no records, got %d", f.Len())
+	}
+	if _, err := f.Get(0); err == nil {
+		t.Error("Expected error for index out of range")
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Get(0); err == nil {
+		t.Error("Expected error for closed file")
+	}
+
+	empty, _ := NewStruct("")
+	if _, err := OpenRecordFile(name, empty, true); err == nil {
+		t.Error("Expected error for a struct of length 0")
+	}
+}
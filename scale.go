@@ -0,0 +1,146 @@
+package pystruct
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// scaledField is an integer item unpacked as the float64 raw*scale + offset
+type scaledField struct {
+	item   int
+	scale  float64
+	offset float64
+	raw    reflect.Type // Go type of the item values
+	min    float64      // smallest raw value
+	limit  float64      // raw values must be less than limit
+}
+
+// WithScale returns a copy of the struct whose item-th value, an integer, is unpacked as the float64 raw*scale + offset.
+// Pack accepts numbers, rounds (value - offset) / scale to the nearest integer and rejects the values out of the range of the item.
+// Dump, ToJSON and ToCSV write the scaled values, FromJSON and FromCSV accept them.
+func (s *PyStruct) WithScale(item int, scale, offset float64) (PyStruct, error) {
+	if scale == 0 || math.IsNaN(scale) || math.IsInf(scale, 0) || math.IsNaN(offset) || math.IsInf(offset, 0) {
+		return PyStruct{}, fmt.Errorf("struct.error: bad scale %g and offset %g", scale, offset)
+	}
+	it, err := s.scalableItem(item)
+	if err != nil {
+		return PyStruct{}, err
+	}
+	bits := it.size * 8
+	if isSignedFormat(it.format) {
+		bits--
+	}
+	return s.withScale(item, scale, offset, bits)
+}
+
+// WithFixedPoint returns a copy of the struct whose item-th value, an integer, is unpacked as a fixed-point number
+// of intBits integer bits and fracBits fractional bits, the Qm.n format of m = intBits and n = fracBits.
+// The sign bit of signed items is not counted in intBits: Q15 in 'h' is (0, 15), Q16.16 in 'i' is (15, 16),
+// the bits of the item above the sign, integer and fractional bits must be unused.
+// Pack and Unpack work like with WithScale(item, 2^-fracBits, 0).
+func (s *PyStruct) WithFixedPoint(item, intBits, fracBits int) (PyStruct, error) {
+	it, err := s.scalableItem(item)
+	if err != nil {
+		return PyStruct{}, err
+	}
+	sign := 0
+	if isSignedFormat(it.format) {
+		sign = 1
+	}
+	if intBits < 0 || fracBits < 0 || sign+intBits+fracBits > it.size*8 {
+		return PyStruct{}, fmt.Errorf(
+			"struct.error: fixed-point of %d integer and %d fractional bits doesn't fit item %d (%s)",
+			intBits, fracBits, item, cFormatStringMap[it.format],
+		)
+	}
+	return s.withScale(item, math.Ldexp(1, -fracBits), 0, intBits+fracBits)
+}
+
+// scalableItem returns the item if it can be scaled
+func (s *PyStruct) scalableItem(i int) (item, error) {
+	if i < 0 || i >= s.items_num {
+		return item{}, fmt.Errorf("struct.error: item index %d out of range [0, %d)", i, s.items_num)
+	}
+	if err := s.checkNotSpecial(i); err != nil {
+		return item{}, err
+	}
+	it := s.item(i)
+	if kind := goTypeMap[it.format].Kind(); kind < reflect.Int8 || kind > reflect.Uint64 || kind == reflect.Uintptr {
+		return item{}, fmt.Errorf("struct.error: item %d (%s) is not an integer", i, cFormatStringMap[it.format])
+	}
+	return it, nil
+}
+
+// withScale returns a copy of the struct with the scaled item, whose raw values use the bits below the sign
+func (s *PyStruct) withScale(item int, scale, offset float64, bits int) (PyStruct, error) {
+	it := s.item(item)
+	sc := scaledField{
+		item:   item,
+		scale:  scale,
+		offset: offset,
+		raw:    goTypeMap[it.format],
+		limit:  math.Ldexp(1, bits),
+	}
+	if isSignedFormat(it.format) {
+		sc.min = -sc.limit
+	}
+
+	result := *s
+	result.scales = append(append([]scaledField(nil), s.scales...), sc)
+	return result, nil
+}
+
+// isSignedFormat reports whether the format is a signed integer
+func isSignedFormat(format cFormatRune) bool {
+	switch goTypeMap[format].Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func (s *PyStruct) scaled(item int) (scaledField, bool) {
+	for _, sc := range s.scales {
+		if sc.item == item {
+			return sc, true
+		}
+	}
+	return scaledField{}, false
+}
+
+// convert returns the decoded raw value as the scaled float64
+func (sc scaledField) convert(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	var raw float64
+	if v.CanInt() {
+		raw = float64(v.Int())
+	} else {
+		raw = float64(v.Uint())
+	}
+	return raw*sc.scale + sc.offset
+}
+
+// rawValue returns the number rounded to the Go type of the item accepted by the packing
+func (sc scaledField) rawValue(value interface{}) (interface{}, error) {
+	v := reflect.ValueOf(value)
+	var f float64
+	switch {
+	case v.CanFloat():
+		f = v.Float()
+	case v.CanInt():
+		f = float64(v.Int())
+	case v.CanUint():
+		f = float64(v.Uint())
+	default:
+		return nil, fmt.Errorf("struct.error: required argument of scaled item %d is not a number", sc.item)
+	}
+	raw := math.Round((f - sc.offset) / sc.scale)
+	if !(raw >= sc.min && raw < sc.limit) {
+		return nil, fmt.Errorf("struct.error: %v is out of range of scaled item %d", value, sc.item)
+	}
+	if sc.min < 0 {
+		return reflect.ValueOf(int64(raw)).Convert(sc.raw).Interface(), nil
+	}
+	return reflect.ValueOf(uint64(raw)).Convert(sc.raw).Interface(), nil
+}
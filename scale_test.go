@@ -0,0 +1,145 @@
+package pystruct
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWithFixedPoint(t *testing.T) {
+	record, _ := NewStructWithNames("<hiH", "gain", "position", "level")
+	s, err := record.WithFixedPoint(0, 0, 15) // Q15
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, err = s.WithFixedPoint(1, 15, 16); err != nil { // Q16.16
+		t.Fatal(err)
+	}
+
+	buffer, err := s.Pack(-0.5, 1.25, uint16(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{0x00, 0xc0, 0x00, 0x40, 0x01, 0x00, 0x07, 0x00}
+	if !bytes.Equal(buffer, expected) {
+		t.Errorf("Expected: %v\nActual: %v\n", expected, buffer)
+	}
+	values, err := s.Unpack(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, []interface{}{-0.5, 1.25, uint16(7)}) {
+		t.Errorf("Unexpected values %v", values)
+	}
+
+	// Q15 ranges over [-1, 1)
+	if _, err := s.Pack(1.0, 0.0, uint16(0)); err == nil {
+		t.Error("Expected an out of range error for 1.0 in Q15")
+	}
+	if _, err := s.Pack(-1.0, 0.0, uint16(0)); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.Pack("1", 0.0, uint16(0)); err == nil {
+		t.Error("Expected an error for a string value")
+	}
+
+	if _, err := record.WithFixedPoint(0, 1, 15); err == nil {
+		t.Error("Expected an error for 17 bits in 'h'")
+	}
+	if _, err := s.WithFixedPoint(0, 0, 15); err == nil {
+		t.Error("Expected an error for an item scaled twice")
+	}
+	unsigned, err := record.WithFixedPoint(2, 8, 8) // UQ8.8
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unsigned.Pack(int16(0), int32(0), -1.0); err == nil {
+		t.Error("Expected an error for a negative unsigned value")
+	}
+}
+
+func TestWithScale(t *testing.T) {
+	reading, _ := NewStructWithNames("<hB", "temperature", "flags")
+	s, err := reading.WithScale(0, 0.01, -40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reading.WithScale(1, 0, 0); err == nil {
+		t.Error("Expected an error for a zero scale")
+	}
+	str, _ := NewStruct("3s")
+	if _, err := str.WithScale(0, 2, 0); err == nil {
+		t.Error("Expected an error for a string item")
+	}
+
+	// (21.504 + 40) / 0.01 is rounded to the raw 6150
+	buffer, err := s.Pack(21.504, uint8(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw, _ := reading.Unpack(buffer); raw[0] != int16(6150) {
+		t.Errorf("Expected the raw value 6150, got %v", raw[0])
+	}
+	values, err := s.Unpack(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := values[0].(float64); v < 21.4999 || v > 21.5001 {
+		t.Errorf("Expected 21.5, got %v", v)
+	}
+	if _, err := s.Pack(400, uint8(0)); err == nil {
+		t.Error("Expected an out of range error for 400")
+	}
+
+	v, _ := NewView(s, buffer)
+	if err := v.Set(0, -40); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := v.Get(0); value != -40.0 {
+		t.Errorf("Expected -40, got %v", value)
+	}
+
+	data, err := s.ToJSON(buffer, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `[{"temperature":-40,"flags":1}]` {
+		t.Errorf("Unexpected JSON %s", data)
+	}
+	decoded, err := s.FromJSON([]byte(`[{"temperature":25.5,"flags":1}]`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw, _ := reading.Unpack(decoded); raw[0] != int16(6550) {
+		t.Errorf("Expected the raw value 6550, got %v", raw[0])
+	}
+
+	var dump strings.Builder
+	if err := s.Dump(&dump, buffer); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(dump.String(), "0 (-40)") {
+		t.Errorf("Expected the raw and scaled values in the dump:\n%s", dump.String())
+	}
+
+	type sample struct {
+		Temperature float64
+		Flags       uint8
+	}
+	var samples []sample
+	if err := s.UnpackSlice(buffer, &samples); err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 1 || samples[0] != (sample{-40, 1}) {
+		t.Errorf("Unexpected samples %v", samples)
+	}
+	type rawSample struct {
+		Temperature int16
+		Flags       uint8
+	}
+	var raws []rawSample
+	if err := s.UnpackSlice(buffer, &raws); err == nil {
+		t.Error("Expected an error for an integer field bound to a scaled item")
+	}
+}
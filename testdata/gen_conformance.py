@@ -0,0 +1,119 @@
+#!/usr/bin/env python3
+"""Generate conformance.json vectors with CPython's struct module.
+
+Run from the repository root:
+
+    python3 testdata/gen_conformance.py > testdata/conformance.json
+
+Values are encoded so that they survive JSON:
+integers as numbers, bools as booleans, floats as their repr() string
+(to keep nan, inf and -0.0) and bytes ('c' and 's') as hex strings.
+Vectors pystruct is known to differ on are kept, conformance_test.go skips them with the reason.
+"""
+import json
+import struct
+import sys
+
+PREFIXES = ["", "@", "=", "<", ">", "!"]
+
+F32_MAX = struct.unpack("<f", b"\xff\xff\x7f\x7f")[0]
+F32_MIN_SUBNORMAL = struct.unpack("<f", b"\x01\x00\x00\x00")[0]
+F32_MIN_NORMAL = struct.unpack("<f", b"\x00\x00\x80\x00")[0]
+
+EDGE_VALUES = {
+    "c": [b"\x00", b"a", b"\xff"],
+    "b": [-128, -1, 0, 1, 127],
+    "B": [0, 1, 128, 255],
+    "?": [False, True],
+    "h": [-32768, -1, 0, 1, 32767],
+    "H": [0, 1, 32768, 65535],
+    "i": [-2**31, -1, 0, 1, 2**31 - 1],
+    "I": [0, 1, 2**31, 2**32 - 1],
+    "l": [-2**31, -1, 0, 1, 2**31 - 1],
+    "L": [0, 1, 2**31, 2**32 - 1],
+    "q": [-2**63, -1, 0, 1, 2**63 - 1],
+    "Q": [0, 1, 2**63, 2**64 - 1],
+    "f": [0.0, -0.0, 1.5, -2.25, F32_MAX, -F32_MAX, F32_MIN_NORMAL, F32_MIN_SUBNORMAL,
+          float("inf"), float("-inf"), float("nan")],
+    "d": [0.0, -0.0, 1.01, -2.5e-300, sys.float_info.max, -sys.float_info.max,
+          sys.float_info.min, 5e-324, float("inf"), float("-inf"), float("nan")],
+}
+
+STRING_VALUES = [
+    ("0s", b""),
+    ("1s", b""),
+    ("1s", b"a"),
+    ("3s", b""),
+    ("3s", b"ab"),
+    ("3s", b"abc"),
+    ("3s", b"abcd"),
+    ("4s", b"\x00\xff\x01\x7f"),
+]
+
+COMPOSITES = [
+    ("bhbibqbd", [-1, 2, -3, 4, -5, 6, -7, 8.5]),
+    ("c?Hd", [b"z", True, 65535, -0.0]),
+    ("3sh", [b"ab", -2]),
+    ("2h3si", [1, -1, b"xyz", 2**31 - 1]),
+    ("b0iB", [1, 2]),
+    ("?3d", [False, float("inf"), float("-inf"), float("nan")]),
+    ("Q2fb", [2**64 - 1, 1.5, -0.0, -128]),
+    ("BIlLq", [255, 2**32 - 1, -2**31, 2**32 - 1, -2**63]),
+]
+
+
+def encode(fmt_char, value):
+    if fmt_char in "cs":
+        return value.hex()
+    if fmt_char in "fd":
+        return repr(value)
+    return value
+
+
+def item_formats(fmt):
+    """Expand fmt (without prefix) into the format character of each value."""
+    chars, count = [], ""
+    for ch in fmt:
+        if ch.isdigit():
+            count += ch
+            continue
+        n = int(count) if count else 1
+        chars.extend([ch] if ch == "s" else [ch] * n)
+        count = ""
+    return chars
+
+
+def vector(prefix, fmt, values):
+    full = prefix + fmt
+    packed = struct.pack(full, *values)
+    unpacked = struct.unpack(full, packed)
+    chars = item_formats(fmt)
+    return {
+        "format": full,
+        "size": struct.calcsize(full),
+        "values": [encode(c, v) for c, v in zip(chars, values)],
+        "packed": packed.hex(),
+        "unpacked": [encode(c, v) for c, v in zip(chars, unpacked)],
+    }
+
+
+def main():
+    vectors = []
+    for prefix in PREFIXES:
+        for fmt_char, values in EDGE_VALUES.items():
+            for value in values:
+                vectors.append(vector(prefix, fmt_char, [value]))
+        for fmt, value in STRING_VALUES:
+            vectors.append(vector(prefix, fmt, [value]))
+        for fmt, values in COMPOSITES:
+            vectors.append(vector(prefix, fmt, values))
+
+    out = sys.stdout
+    out.write('{"python": %s, "byteorder": %s, "vectors": [\n' % (
+        json.dumps(sys.version.split()[0]), json.dumps(sys.byteorder)))
+    out.write(",\n".join(json.dumps(v) for v in vectors))
+    out.write("\n]}\n")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,126 @@
+package pystruct
+
+import (
+	"fmt"
+)
+
+// UnknownTagError is returned by Union when the discriminator value has no registered body
+type UnknownTagError struct {
+	Tag interface{} // value of the discriminator field
+}
+
+func (e *UnknownTagError) Error() string {
+	return fmt.Sprintf("struct.error: unknown tag %v", e.Tag)
+}
+
+// Message is a header and a body decoded by a Union
+type Message struct {
+	Tag    interface{}   // value of the discriminator field
+	Header []interface{} // values of the header, including the discriminator
+	Body   []interface{} // values of the body selected by the tag
+}
+
+// Union packs messages made of a header followed by a body,
+// the body layout is selected by the value of a discriminator field of the header.
+// Bodies must be registered before the union is used, Decode and Encode are safe for concurrent use.
+type Union struct {
+	header PyStruct
+	tag    item                // discriminator item of the header
+	bodies map[string]PyStruct // keyed by the packed bytes of the tag
+}
+
+// NewUnion returns a union of messages with the header,
+// tag is the index of the discriminator value in the header, like in the result of Fields
+func NewUnion(header PyStruct, tag int) (*Union, error) {
+	if tag < 0 || tag >= header.items_num {
+		return nil, fmt.Errorf("struct.error: item index %d out of range [0, %d)", tag, header.items_num)
+	}
+	return &Union{header: header, tag: header.item(tag), bodies: make(map[string]PyStruct)}, nil
+}
+
+// tagKey returns the tag value packed as in the header, so that tags of different Go types
+// packed to the same bytes are equal, including the values not comparable with == like *big.Int
+func (u *Union) tagKey(tag interface{}) (string, error) {
+	scratch := make([]byte, u.header.size)
+	if err := u.header.encodeItem(scratch, u.tag, tag); err != nil {
+		return "", err
+	}
+	return u.rawTag(scratch), nil
+}
+
+// rawTag returns the bytes of the tag in the packed header
+func (u *Union) rawTag(buffer []byte) string {
+	return string(buffer[u.tag.offset : u.tag.offset+u.tag.size])
+}
+
+// Register maps the tag value to the body layout
+func (u *Union) Register(tag interface{}, body PyStruct) error {
+	key, err := u.tagKey(tag)
+	if err != nil {
+		return err
+	}
+	if _, ok := u.bodies[key]; ok {
+		return fmt.Errorf("struct.error: tag %v is already registered", tag)
+	}
+	u.bodies[key] = body
+	return nil
+}
+
+// Body returns the body layout registered for the tag value
+func (u *Union) Body(tag interface{}) (PyStruct, bool) {
+	key, err := u.tagKey(tag)
+	if err != nil {
+		return PyStruct{}, false
+	}
+	body, ok := u.bodies[key]
+	return body, ok
+}
+
+// DecodeFrom decodes the message at the start of buffer and returns the number of bytes consumed,
+// the bytes after the message are ignored
+func (u *Union) DecodeFrom(buffer []byte) (Message, int, error) {
+	header, _, err := u.header.UnpackFromN(buffer, 0)
+	if err != nil {
+		return Message{}, 0, err
+	}
+	tag := u.header.decodeItem(buffer, u.tag)
+	body, ok := u.bodies[u.rawTag(buffer)]
+	if !ok {
+		return Message{}, 0, &UnknownTagError{tag}
+	}
+	values, n, err := body.UnpackFromN(buffer, u.header.size)
+	if err != nil {
+		return Message{}, 0, err
+	}
+	return Message{Tag: tag, Header: header, Body: values}, u.header.size + n, nil
+}
+
+// Decode decodes the message, the buffer’s size in bytes must match the header and body sizes
+func (u *Union) Decode(buffer []byte) (Message, error) {
+	m, n, err := u.DecodeFrom(buffer)
+	if err != nil {
+		return Message{}, err
+	}
+	if n != len(buffer) {
+		return Message{}, fmt.Errorf("struct.error: message of tag %v requires a buffer of %d bytes", m.Tag, n)
+	}
+	return m, nil
+}
+
+// Encode packs the header values followed by the body values,
+// the body layout is selected by the discriminator value of the header
+func (u *Union) Encode(header []interface{}, body ...interface{}) ([]byte, error) {
+	buffer, err := u.header.AppendPack(nil, header...)
+	if err != nil {
+		return nil, err
+	}
+	tag := u.header.decodeItem(buffer, u.tag)
+	layout, ok := u.bodies[u.rawTag(buffer)]
+	if !ok {
+		return nil, &UnknownTagError{tag}
+	}
+	if buffer, err = layout.AppendPack(buffer, body...); err != nil {
+		return nil, err
+	}
+	return buffer, nil
+}
@@ -0,0 +1,116 @@
+package pystruct
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestUnion(t *testing.T) {
+	header, _ := NewStruct("<BH")
+	ping, _ := NewStruct("<I")
+	text, _ := NewStruct("<8s")
+
+	u, err := NewUnion(header, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := u.Register(uint8(1), ping); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.Register(uint8(2), text); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.Register(uint8(1), text); err == nil {
+		t.Error("Expected error for a registered tag")
+	}
+	if err := u.Register("x", text); err == nil {
+		t.Error("Expected error for a tag of a wrong type")
+	}
+	if body, ok := u.Body(uint8(2)); !ok || body.Format() != text.Format() {
+		t.Errorf("Unexpected body %q for tag 2", body.Format())
+	}
+
+	buffer, err := u.Encode([]interface{}{uint8(2), uint16(7)}, "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buffer) != header.Size()+text.Size() {
+		t.Errorf("Expected %d bytes, got %d", header.Size()+text.Size(), len(buffer))
+	}
+
+	m, err := u.Decode(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Message{
+		Tag:    uint8(2),
+		Header: []interface{}{uint8(2), uint16(7)},
+		Body:   []interface{}{"hello\x00\x00\x00"},
+	}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("Expected: %v\nActual: %v\n", expected, m)
+	}
+
+	buffer, _ = u.Encode([]interface{}{uint8(1), uint16(0)}, uint32(9))
+	m, n, err := u.DecodeFrom(append(buffer, 0xff))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(buffer) || !reflect.DeepEqual(m.Body, []interface{}{uint32(9)}) {
+		t.Errorf("Unexpected message %v of %d bytes", m, n)
+	}
+	if _, err := u.Decode(append(buffer, 0xff)); err == nil {
+		t.Error("Expected error for trailing bytes")
+	}
+	if _, err := u.Decode(buffer[:4]); err == nil {
+		t.Error("Expected error for a short buffer")
+	}
+
+	var unknown *UnknownTagError
+	if _, err := u.Decode([]byte{3, 0, 0}); !errors.As(err, &unknown) || unknown.Tag != uint8(3) {
+		t.Errorf("Expected unknown tag error, got %v", err)
+	}
+	if _, err := u.Encode([]interface{}{uint8(3), uint16(0)}); !errors.As(err, &unknown) {
+		t.Errorf("Expected unknown tag error, got %v", err)
+	}
+	if _, err := u.Encode([]interface{}{uint8(1), uint16(0)}, "x"); err == nil {
+		t.Error("Expected error for a body value of a wrong type")
+	}
+
+	if _, err := NewUnion(header, 2); err == nil {
+		t.Error("Expected error for tag index out of range")
+	}
+}
+
+func TestUnionBigIntTag(t *testing.T) {
+	raw, _ := NewStruct(">3s")
+	header, _ := raw.WithBigInt(0, false)
+	u, err := NewUnion(header, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := NewStruct(">H")
+	if err := u.Register(big.NewInt(0x010203), body); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := u.Body(0x010203); !ok {
+		t.Error("Expected the body of an equal tag of another type")
+	}
+	if err := u.Register(uint32(0x010203), body); err == nil {
+		t.Error("Expected error for a tag already registered")
+	}
+
+	buffer, err := u.Encode([]interface{}{big.NewInt(0x010203)}, uint16(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := u.Decode(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Tag.(*big.Int).Int64() != 0x010203 || !reflect.DeepEqual(m.Body, []interface{}{uint16(5)}) {
+		t.Errorf("Unexpected message %v", m)
+	}
+}
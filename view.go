@@ -0,0 +1,147 @@
+package pystruct
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// View gives access to single values of a struct packed in a buffer,
+// each value is decoded or encoded in place at its offset without unpacking the whole struct.
+// Values are indexed like the result of Fields, constant values included.
+type View struct {
+	s      PyStruct
+	buffer []byte
+}
+
+// NewView returns a view of the struct packed at the start of buffer,
+// the buffer must hold at least Size() bytes and is shared, not copied
+func NewView(s PyStruct, buffer []byte) (*View, error) {
+	if len(buffer) < s.size {
+		return nil, fmt.Errorf("struct.error: view requires a buffer of at least %d bytes (actual buffer size is %d)", s.size, len(buffer))
+	}
+	return &View{s: s, buffer: buffer[:s.size:s.size]}, nil
+}
+
+// Bytes returns the viewed bytes
+func (v *View) Bytes() []byte {
+	return v.buffer
+}
+
+// Len returns the number of values in the view
+func (v *View) Len() int {
+	return v.s.items_num
+}
+
+func (v *View) item(i int) (item, error) {
+	if i < 0 || i >= v.s.items_num {
+		return item{}, fmt.Errorf("struct.error: item index %d out of range [0, %d)", i, v.s.items_num)
+	}
+	return v.s.item(i), nil
+}
+
+// Get decodes the i-th value
+func (v *View) Get(i int) (interface{}, error) {
+	it, err := v.item(i)
+	if err != nil {
+		return nil, err
+	}
+	return v.s.decodeItem(v.buffer, it), nil
+}
+
+// encodeItem encodes the value of the item into buffer,
+// a constant item only accepts its constant value and reports a *ConstantError for the others
+func (v *View) encodeItem(buffer []byte, it item, value interface{}) error {
+	c, ok := v.s.constant(it.seq)
+	if !ok {
+		return v.s.encodeItem(buffer, it, value)
+	}
+	scratch := make([]byte, it.offset+it.size)
+	if err := v.s.encodeItem(scratch, it, value); err != nil {
+		return err
+	}
+	if !bytes.Equal(scratch[it.offset:], c.data) {
+		return &ConstantError{it.seq, c.value, value}
+	}
+	copy(buffer[it.offset:], c.data)
+	return nil
+}
+
+// Set encodes the i-th value in place, the checksums of the struct are updated
+// and the constants can't be changed
+func (v *View) Set(i int, value interface{}) error {
+	it, err := v.item(i)
+	if err != nil {
+		return err
+	}
+	if err := v.encodeItem(v.buffer, it, value); err != nil {
+		return err
+	}
+	v.s.fillChecksums(v.buffer)
+	return nil
+}
+
+// namedItems returns the items of the named field and whether the field holds a single value
+func (v *View) namedItems(name string) ([]item, bool, error) {
+	for g, group := range v.s.groups {
+		if name == "" || v.s.fieldName(g) != name {
+			continue
+		}
+		if group.format == tString || group.number == 1 {
+			return []item{v.s.item(group.first)}, true, nil
+		}
+		items := make([]item, group.number)
+		for i := range items {
+			items[i] = v.s.item(group.first + i)
+		}
+		return items, false, nil
+	}
+	return nil, false, fmt.Errorf("struct.error: struct has no field %q", name)
+}
+
+// GetByName decodes the value of the named field,
+// a field with a repeat count like "3h" is returned as []interface{}
+func (v *View) GetByName(name string) (interface{}, error) {
+	items, single, err := v.namedItems(name)
+	if err != nil {
+		return nil, err
+	}
+	if single {
+		return v.s.decodeItem(v.buffer, items[0]), nil
+	}
+	values := make([]interface{}, len(items))
+	for i, it := range items {
+		values[i] = v.s.decodeItem(v.buffer, it)
+	}
+	return values, nil
+}
+
+// SetByName encodes the value of the named field in place, like Set,
+// a field with a repeat count like "3h" takes a []interface{} of all its values
+func (v *View) SetByName(name string, value interface{}) error {
+	items, single, err := v.namedItems(name)
+	if err != nil {
+		return err
+	}
+	if single {
+		if err := v.encodeItem(v.buffer, items[0], value); err != nil {
+			return err
+		}
+		v.s.fillChecksums(v.buffer)
+		return nil
+	}
+	values, ok := value.([]interface{})
+	if !ok || len(values) != len(items) {
+		return fmt.Errorf("struct.error: field %q requires %d values", name, len(items))
+	}
+	// encode into a copy of the field first, so the buffer is left untouched on error
+	start, end := items[0].offset, items[len(items)-1].offset+items[len(items)-1].size
+	scratch := make([]byte, end)
+	for i, it := range items {
+		if err := v.encodeItem(scratch, it, values[i]); err != nil {
+			return err
+		}
+	}
+	copy(v.buffer[start:end], scratch[start:end])
+	v.s.fillChecksums(v.buffer)
+	return nil
+}
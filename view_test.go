@@ -0,0 +1,95 @@
+package pystruct
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestView(t *testing.T) {
+	s, err := NewStructWithNames("@b3si2h", "kind", "label", "value", "xy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer, err := s.Pack(int8(1), "ab", int32(-5), int16(2), int16(-3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer = append(buffer, 0xff)
+
+	v, err := NewView(s, buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Len() != 5 || len(v.Bytes()) != s.Size() {
+		t.Errorf("Unexpected view of %d values over %d bytes", v.Len(), len(v.Bytes()))
+	}
+
+	expected := s.unpack(buffer[:s.Size()])
+	for i := range expected {
+		value, err := v.Get(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != expected[i] {
+			t.Errorf("Item %d expected: %v, actual: %v", i, expected[i], value)
+		}
+	}
+	if _, err := v.Get(5); err == nil {
+		t.Error("Expected error for index out of range")
+	}
+
+	if err := v.Set(2, int32(7)); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Set(1, "xyzw"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Set(0, "x"); err == nil {
+		t.Error("Expected error for a value of a wrong type")
+	}
+	if err := v.SetByName("xy", []interface{}{int16(4), int16(5)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.SetByName("xy", []interface{}{int16(6), "x"}); err == nil {
+		t.Error("Expected error for a value of a wrong type")
+	}
+	if err := v.SetByName("kind", int8(9)); err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := s.Unpack(buffer[:s.Size()])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, []interface{}{int8(9), "xyz", int32(7), int16(4), int16(5)}) {
+		t.Errorf("Unexpected values after set %v", values)
+	}
+	if buffer[len(buffer)-1] != 0xff {
+		t.Error("Bytes after the view were modified")
+	}
+
+	xy, err := v.GetByName("xy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(xy, []interface{}{int16(4), int16(5)}) {
+		t.Errorf("Unexpected xy %v", xy)
+	}
+	label, err := v.GetByName("label")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if label != "xyz" {
+		t.Errorf("Unexpected label %v", label)
+	}
+	if _, err := v.GetByName(""); err == nil {
+		t.Error("Expected error for an empty name")
+	}
+	if _, err := v.GetByName("missing"); err == nil {
+		t.Error("Expected error for an unknown name")
+	}
+
+	if _, err := NewView(s, buffer[:3]); err == nil {
+		t.Error("Expected error for a short buffer")
+	}
+}